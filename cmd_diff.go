@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pc4_etl/internal/diff"
+)
+
+// runDiff implementa el subcomando "diff <old-dir> <new-dir>": compara dos salidas documento por
+// documento (movies por movieId, ratings por userId+movieId, users por userId, similarities y
+// user_ratings por _id) y resume qué campos cambiaron
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	ignore := fs.String("ignore", "createdAt,updatedAt", "Campos de primer nivel a ignorar, separados por coma")
+	samples := fs.Int("samples", 5, "Número de ejemplos por colección")
+	jsonOut := fs.String("json", "", "Ruta opcional para guardar el reporte en JSON")
+	failOnChange := fs.Bool("fail-on-change", false, "Terminar con código de salida 1 si hay diferencias")
+	fs.Parse(args)
+
+	// Los flags pueden ir antes, entre o después de los directorios
+	var dirs []string
+	for fs.NArg() > 0 {
+		dirs = append(dirs, fs.Arg(0))
+		fs.Parse(fs.Args()[1:])
+	}
+	if len(dirs) != 2 {
+		fmt.Fprintln(os.Stderr, "uso: etl diff [flags] <old-dir> <new-dir>")
+		exit(1)
+	}
+
+	var ignored []string
+	for _, f := range strings.Split(*ignore, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			ignored = append(ignored, f)
+		}
+	}
+
+	fmt.Printf("=== Diferencias: %s -> %s ===\n", dirs[0], dirs[1])
+	rep, err := diff.Compare(dirs[0], dirs[1], ignored, *samples)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error comparando salidas:", err)
+		exit(1)
+	}
+	if len(rep.Collections) == 0 {
+		fmt.Fprintln(os.Stderr, "error: no hay NDJSON para comparar en", dirs[0], "ni en", dirs[1])
+		exit(1)
+	}
+	printLines(rep.Lines())
+
+	if *jsonOut != "" {
+		if err := os.MkdirAll(filepath.Dir(*jsonOut), 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo crear el directorio de %s: %v\n", *jsonOut, err)
+		} else if err := rep.WriteJSON(*jsonOut); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo guardar %s: %v\n", *jsonOut, err)
+		} else {
+			fmt.Printf("\n  ✓ Reporte JSON guardado en %s\n", *jsonOut)
+		}
+	}
+
+	fmt.Println()
+	if changes := rep.Changes(); changes > 0 {
+		fmt.Printf("⚠ %d documentos con diferencias\n", changes)
+		if *failOnChange {
+			exit(1)
+		}
+		return
+	}
+	fmt.Println("✓ Sin diferencias")
+}
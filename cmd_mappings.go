@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pc4_etl/internal/inputs"
+	"pc4_etl/internal/loaders"
+	"pc4_etl/internal/mappers"
+	"pc4_etl/internal/validation"
+)
+
+// runMappings implementa el subcomando "mappings check|repair": verifica item_map.csv y
+// user_map.csv contra el dataset y, con repair, los compacta escribiendo tablas de remapeo
+func runMappings(args []string) {
+	if len(args) == 0 || (args[0] != "check" && args[0] != "repair") {
+		fmt.Fprintln(os.Stderr, "uso: etl mappings check|repair [flags]")
+		exit(1)
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("mappings "+action, flag.ExitOnError)
+	dataDir := fs.String("data-dir", "data", "Directorio con los csv (default: data)")
+	var columnOverrides inputs.ColumnOverrides
+	fs.Var(&columnOverrides, "column", "Override de columna por cabecera, repetible (ej: --column ratings.movieId=item_id)")
+	registerInputFlags(fs)
+	moviesFile := fs.String("movies-file", "movies.csv", "Nombre de movies.csv")
+	ratingsFile := fs.String("ratings-file", "ratings.csv", "Nombre de ratings.csv")
+	itemMapFile := fs.String("item-map-file", "item_map.csv", "Nombre de item_map.csv")
+	userMapFile := fs.String("user-map-file", "user_map.csv", "Nombre de user_map.csv")
+	similaritiesFile := fs.String("similarities-file", "item_topk_cosine_conc.csv", "Nombre de item_topk_cosine_conc.csv (vacío = no verificar)")
+	mappingsDir := fs.String("mappings-dir", "", "Directorio de item_map.csv, user_map.csv y similitudes (vacío = --data-dir, o el directorio del .zip)")
+	indexBase := fs.Int("index-base", 0, "Primer índice de iIdx/uIdx: 0 o 1 (repair compacta desde esta base)")
+	items := fs.Bool("items", true, "Procesar item_map.csv")
+	users := fs.Bool("users", true, "Procesar user_map.csv (los usuarios del dataset se leen de ratings.csv)")
+	samples := fs.Int("samples", 5, "Número de ejemplos por chequeo")
+	jsonOut := fs.String("json", "", "Ruta opcional para guardar el reporte en JSON")
+	outDir := fs.String("out-dir", "", "repair (obligatorio): directorio donde escribir los mapeos compactados, las similitudes remapeadas y las tablas de remapeo; los archivos existentes se guardan como .bak")
+	dropAbsent := fs.Bool("drop-absent", false, "repair: descartar los ids que ya no están en el dataset")
+	fs.Parse(args[1:])
+	atExit(inputs.Cleanup)
+
+	if !mappers.ValidIndexBase(*indexBase) {
+		fmt.Fprintln(os.Stderr, "error: --index-base debe ser 0 o 1")
+		exit(1)
+	}
+	if action == "repair" && *outDir == "" {
+		fmt.Fprintln(os.Stderr, "error: mappings repair requiere --out-dir (los mapeos y similitudes originales no se sobrescriben)")
+		exit(1)
+	}
+
+	mapsDir := inputs.MappingsDir(*dataDir, *mappingsDir)
+
+	type target struct {
+		mapping   validation.Mapping
+		file      string
+		remapFile string
+	}
+	var targets []target
+	load := func(file, name, idColumn, idxColumn, datasetFile, datasetKind string, loadMap func(string) (map[int]int, error)) {
+		m, err := loadMap(inputs.Resolve(mapsDir, file))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error cargando %s: %v\n", file, err)
+			exit(1)
+		}
+		mapping := validation.Mapping{Name: name, IDColumn: idColumn, IdxColumn: idxColumn, Map: m}
+		dataset, err := validation.DatasetIDs(inputs.Resolve(*dataDir, datasetFile), datasetKind, idColumn)
+		if err != nil {
+			mapping.Skipped = datasetFile + ": " + err.Error()
+		} else {
+			mapping.Dataset = dataset
+		}
+		targets = append(targets, target{mapping, file, name[:len(name)-len("_map")] + "_remap.csv"})
+	}
+	if *items {
+		load(*itemMapFile, "item_map", "movieId", "iIdx", *moviesFile, "movies", loaders.LoadItemMap)
+	}
+	if *users {
+		load(*userMapFile, "user_map", "userId", "uIdx", *ratingsFile, "ratings", loaders.LoadUserMap)
+	}
+
+	maps := make([]validation.Mapping, len(targets))
+	for i, t := range targets {
+		maps[i] = t.mapping
+	}
+	fmt.Println("=== Mapeos:", *dataDir, "===")
+	similarities := ""
+	if *items && *similaritiesFile != "" {
+		similarities = inputs.Resolve(mapsDir, *similaritiesFile)
+	}
+	rep := validation.CheckMappings(maps, *indexBase, similarities, *samples)
+	printLines(rep.Lines())
+	if *jsonOut != "" {
+		if err := writeIntegrityJSON(*jsonOut, []*validation.IntegrityReport{rep}); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo guardar %s: %v\n", *jsonOut, err)
+		} else {
+			fmt.Printf("\n  ✓ Reporte JSON guardado en %s\n", *jsonOut)
+		}
+	}
+
+	if action == "check" {
+		fmt.Println()
+		if problems := rep.Errors(); problems > 0 {
+			fmt.Printf("✗ FAIL: %d problemas (use \"mappings repair\" para compactar)\n", problems)
+			exit(1)
+		}
+		fmt.Println("✓ PASS")
+		return
+	}
+
+	// repair: compactar índices y escribir mapeo + tabla de remapeo (+ similitudes si cambió item_map)
+	dir := *outDir
+	fmt.Println()
+	fmt.Println("=== Reparación ===")
+	for _, t := range targets {
+		m := t.mapping
+		var keep func(int) bool
+		if *dropAbsent && m.Dataset != nil {
+			keep = func(id int) bool {
+				_, ok := m.Dataset[id]
+				return ok
+			}
+		}
+		compacted, remap := mappers.Compact(m.Map, *indexBase, keep)
+		if len(remap) == 0 {
+			fmt.Printf("  ✓ %s ya es compacto (%d ids)\n", t.file, len(compacted))
+			continue
+		}
+
+		mapPath := filepath.Join(dir, t.file)
+		save := mappers.SaveItemMap
+		if m.Name == "user_map" {
+			save = mappers.SaveUserMap
+		}
+		replaceFile(mapPath, func(tmp string) error { return save(tmp, compacted) })
+		remapPath := filepath.Join(dir, t.remapFile)
+		replaceFile(remapPath, func(tmp string) error {
+			return mappers.SaveRemap(tmp, m.IDColumn, m.IdxColumn, remap)
+		})
+		dropped := len(m.Map) - len(compacted)
+		fmt.Printf("  ✓ %s compactado: %d ids en %s %d..%d, %d reasignados, %d descartados\n",
+			mapPath, len(compacted), m.IdxColumn, *indexBase, *indexBase+len(compacted)-1, len(remap)-dropped, dropped)
+		fmt.Printf("  ✓ Tabla de remapeo guardada en %s\n", remapPath)
+
+		// Las similitudes están indexadas por iIdx: se reescriben con los índices nuevos para que
+		// el ETL no asocie vecinos de otras películas
+		if m.Name == "item_map" && similarities != "" {
+			simPath := filepath.Join(dir, filepath.Base(*similaritiesFile))
+			var written, skipped int
+			ok := replaceFile(simPath, func(tmp string) error {
+				var err error
+				written, skipped, err = loaders.RemapSimilarities(similarities, tmp, m.Map, compacted)
+				return err
+			})
+			if ok {
+				fmt.Printf("  ✓ Similitudes remapeadas en %s: %d filas, %d omitidas (iIdx ambiguo, descartado o inexistente)\n", simPath, written, skipped)
+			}
+		}
+	}
+	fmt.Println("  ⚠ Use --mappings-dir con el directorio reparado, regenere las salidas y reentrene o reordene los modelos con las tablas de remapeo")
+}
+
+// replaceFile escribe path mediante write en un archivo temporal y lo reemplaza, guardando el
+// anterior como path.bak; un error de escritura es fatal, salvo que el origen no exista (false)
+func replaceFile(path string, write func(tmp string) error) bool {
+	tmp := path + ".tmp"
+	if err := write(tmp); err != nil {
+		os.Remove(tmp)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Advertencia: se omite %s: %v\n", path, err)
+			return false
+		}
+		fmt.Fprintf(os.Stderr, "error guardando %s: %v\n", path, err)
+		exit(1)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Rename(path, path+".bak"); err != nil {
+			fmt.Fprintf(os.Stderr, "error respaldando %s: %v\n", path, err)
+			exit(1)
+		}
+		fmt.Printf("  • %s anterior guardado como %s.bak\n", path, path)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		fmt.Fprintf(os.Stderr, "error guardando %s: %v\n", path, err)
+		exit(1)
+	}
+	return true
+}
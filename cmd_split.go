@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"pc4_etl/internal/inputs"
+	"pc4_etl/internal/loaders"
+	"pc4_etl/internal/mappers"
+	"pc4_etl/internal/processors"
+	"pc4_etl/internal/utils"
+)
+
+// runSplit implementa el subcomando "split": particiona ratings en train/validation/test
+func runSplit(args []string) {
+	startTime := time.Now()
+
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	dataDir := fs.String("data-dir", "data", "Directorio con los csv (default: data)")
+	datasetFormat := fs.String("dataset-format", loaders.FormatAuto, "Formato del dataset: auto, csv, ml-100k, ml-1m, generic-csv, jsonl o ndjson")
+	var columnOverrides inputs.ColumnOverrides
+	fs.Var(&columnOverrides, "column", "Override de columna por cabecera, repetible (ej: --column ratings.movieId=item_id)")
+	srcFlags := registerSourceFlags(fs)
+	registerInputFlags(fs)
+	moviesFile := fs.String("movies-file", "movies.csv", "Nombre de movies.csv (orden de los iIdx nuevos)")
+	ratingsFile := fs.String("ratings-file", "ratings.csv", "Nombre de ratings.csv")
+	itemMapFile := fs.String("item-map-file", "item_map.csv", "Nombre de item_map.csv")
+	userMapFile := fs.String("user-map-file", "user_map.csv", "Nombre de user_map.csv")
+	mappingsDir := fs.String("mappings-dir", "", "Directorio de item_map.csv, user_map.csv y similitudes (vacío = --data-dir, o el directorio del .zip)")
+	outDir := fs.String("out-dir", filepath.Join("out", "splits"), "Directorio de salida de los splits")
+	strategy := fs.String("strategy", processors.SplitTemporal, "Estrategia: random, temporal o leave-one-out")
+	seed := fs.Int64("seed", 42, "Semilla para la estrategia random")
+	minRatings := fs.Int("min-ratings", 5, "Mínimo de ratings por usuario (usuarios con menos se descartan)")
+	valRatio := fs.Float64("val-ratio", 0.1, "random: fracción de ratings por usuario para validation")
+	testRatio := fs.Float64("test-ratio", 0.1, "random: fracción de ratings por usuario para test")
+	valN := fs.Int("val-n", 1, "temporal: últimos N ratings (antes de test) para validation")
+	testN := fs.Int("test-n", 1, "temporal: últimos N ratings por usuario para test")
+	updateMappings := fs.Bool("update-mappings", false, "Actualizar item_map.csv y user_map.csv con nuevos IDs encontrados")
+	indexBase := fs.Int("index-base", 0, "Primer índice de iIdx/uIdx para ids sin mapeo: 0 o 1")
+	fs.Parse(args)
+	atExit(inputs.Cleanup)
+
+	if !mappers.ValidIndexBase(*indexBase) {
+		fmt.Fprintln(os.Stderr, "error: --index-base debe ser 0 o 1")
+		exit(1)
+	}
+
+	moviesPath := inputs.Resolve(*dataDir, *moviesFile)
+	ratingsPath := inputs.Resolve(*dataDir, *ratingsFile)
+	mapsDir := inputs.MappingsDir(*dataDir, *mappingsDir)
+	itemMapPath := inputs.Resolve(mapsDir, *itemMapFile)
+	userMapPath := inputs.Resolve(mapsDir, *userMapFile)
+
+	dataset, err := resolveDatasetInputs(*datasetFormat, *dataDir, moviesPath, ratingsPath, srcFlags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error preparando dataset:", err)
+		exit(1)
+	}
+	atExit(dataset.Cleanup)
+	moviesPath, ratingsPath = dataset.Movies, dataset.Ratings
+
+	fmt.Printf("=== Split de ratings (%s) ===\n", *strategy)
+	fmt.Println()
+	if dataset.Source != nil {
+		reportSourceKeys(dataset.Source, *outDir, srcFlags.config(dataset.Format, *dataDir, "", "").KeysDir, *updateMappings)
+		fmt.Println()
+	}
+
+	fmt.Println("Cargando mapeos...")
+	itemMap, err := loaders.LoadItemMap(itemMapPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar item_map.csv: %v\n", err)
+		itemMap = make(map[int]int)
+	}
+	userMap, err := loaders.LoadUserMap(userMapPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar user_map.csv: %v\n", err)
+		userMap = make(map[int]int)
+	}
+	if dataset.Export != nil {
+		itemMap, userMap = dataset.Export.ItemMap(), dataset.Export.UserMap()
+	}
+	itemMapper := mappers.NewIDMapper(itemMap, *indexBase)
+	userMapper := mappers.NewIDMapper(userMap, *indexBase)
+	fmt.Printf("  ✓ %d items y %d usuarios mapeados\n", itemMapper.Count(), userMapper.Count())
+	if dataset.Export == nil {
+		// Los ids sin mapeo reciben los mismos índices que les daría el ETL: iIdx en el orden de
+		// movies.csv (ProcessMovies) y uIdx por userId ascendente (ProcessUsers)
+		if added, err := loaders.AssignItemIndices(moviesPath, itemMapper); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudieron asignar iIdx desde movies.csv: %v\n", err)
+		} else if added > 0 {
+			fmt.Printf("  ✓ %d iIdx nuevos asignados desde movies.csv\n", added)
+		}
+		if added, err := loaders.AssignUserIndices(ratingsPath, userMapper); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudieron asignar uIdx desde ratings.csv: %v\n", err)
+		} else if added > 0 {
+			fmt.Printf("  ✓ %d uIdx nuevos asignados desde ratings.csv\n", added)
+		}
+	}
+
+	fmt.Println("Cargando ratings:", ratingsPath)
+	ratings, invalid, err := loaders.LoadRatings(ratingsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error cargando ratings:", err)
+		exit(1)
+	}
+	fmt.Printf("  ✓ %d ratings cargados\n", len(ratings))
+	if invalid > 0 {
+		fmt.Printf("  ⚠ %d ratings no numéricos omitidos\n", invalid)
+	}
+
+	fmt.Println("Generando splits...")
+	opts := processors.SplitOptions{
+		Strategy:   *strategy,
+		Seed:       *seed,
+		MinRatings: *minRatings,
+		ValRatio:   *valRatio,
+		TestRatio:  *testRatio,
+		ValN:       *valN,
+		TestN:      *testN,
+	}
+	result, err := processors.SplitRatings(ratings, *outDir, opts, userMapper, itemMapper)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error generando splits:", err)
+		exit(1)
+	}
+	fmt.Printf("  ✓ Usuarios: %d (descartados por --min-ratings: %d)\n", result.Users, result.DroppedUsers)
+	if result.Unmapped > 0 {
+		fmt.Printf("  ⚠ %d ratings omitidos: userId/movieId sin uIdx/iIdx (películas ausentes de movies.csv)\n", result.Unmapped)
+	}
+	fmt.Printf("  ✓ Train: %d | Validation: %d | Test: %d\n", result.Train, result.Validation, result.Test)
+	fmt.Printf("  ✓ Splits escritos en %s (NDJSON + CSV)\n", *outDir)
+
+	if *updateMappings {
+		if itemMapper.HasChanged() {
+			if err := mappers.SaveItemMap(itemMapPath, itemMapper.GetMapping()); err != nil {
+				fmt.Fprintf(os.Stderr, "Advertencia: no se pudo actualizar item_map.csv: %v\n", err)
+			} else {
+				fmt.Printf("  ✓ item_map.csv actualizado (%d películas)\n", itemMapper.Count())
+			}
+		}
+		if userMapper.HasChanged() {
+			if err := mappers.SaveUserMap(userMapPath, userMapper.GetMapping()); err != nil {
+				fmt.Fprintf(os.Stderr, "Advertencia: no se pudo actualizar user_map.csv: %v\n", err)
+			} else {
+				fmt.Printf("  ✓ user_map.csv actualizado (%d usuarios)\n", userMapper.Count())
+			}
+		}
+	} else if itemMapper.HasChanged() || userMapper.HasChanged() {
+		fmt.Println("  ⚠ Se asignaron índices nuevos; use --update-mappings para persistirlos")
+	}
+
+	fmt.Println()
+	fmt.Printf("Tiempo total de ejecución: %s\n", utils.FormatDuration(time.Since(startTime)))
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pc4_etl/internal/inputs"
+	"pc4_etl/internal/validation"
+)
+
+// runValidate implementa el subcomando "validate": integridad referencial de entradas y salidas, y
+// esquema e invariantes de los NDJSON generados. "validate <out-dir>" valida solo las salidas.
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	dataDir := fs.String("data-dir", "data", "Directorio con los csv (default: data)")
+	var columnOverrides inputs.ColumnOverrides
+	fs.Var(&columnOverrides, "column", "Override de columna por cabecera, repetible (ej: --column ratings.movieId=item_id)")
+	registerInputFlags(fs)
+	moviesFile := fs.String("movies-file", "movies.csv", "Nombre de movies.csv")
+	ratingsFile := fs.String("ratings-file", "ratings.csv", "Nombre de ratings.csv")
+	linksFile := fs.String("links-file", "links.csv", "Nombre de links.csv")
+	tagsFile := fs.String("tags-file", "tags.csv", "Nombre de tags.csv")
+	genomeTagsFile := fs.String("genome-tags-file", "genome-tags.csv", "Nombre de genome-tags.csv")
+	genomeScoresFile := fs.String("genome-scores-file", "genome-scores.csv", "Nombre de genome-scores.csv")
+	itemMapFile := fs.String("item-map-file", "item_map.csv", "Nombre de item_map.csv")
+	userMapFile := fs.String("user-map-file", "user_map.csv", "Nombre de user_map.csv")
+	similaritiesFile := fs.String("similarities-file", "item_topk_cosine_conc.csv", "Nombre de item_topk_cosine_conc.csv")
+	mappingsDir := fs.String("mappings-dir", "", "Directorio de item_map.csv, user_map.csv y similitudes (vacío = --data-dir, o el directorio del .zip)")
+	checkInputs := fs.Bool("inputs", true, "Verificar los CSV de entrada en --data-dir")
+	outDir := fs.String("out-dir", "", "Directorio con NDJSON generados a verificar (vacío = no verificar salidas)")
+	orphans := fs.String("orphans", validation.PolicyFail, "Política ante huérfanos: fail (código de salida 1) o keep (solo reportar)")
+	duplicates := fs.String("duplicates", validation.DupFail, "Política ante claves repetidas en las entradas: fail (código de salida 1) o cualquier otra (solo reportar)")
+	ratingScale := fs.String("rating-scale", "movielens", "Escala válida de ratings en ratings.ndjson: movielens, 5-star, min:max[:step] o none")
+	samples := fs.Int("samples", 5, "Número de ejemplos por chequeo")
+	jsonOut := fs.String("json", "", "Ruta opcional para guardar el reporte en JSON")
+	fs.Parse(args)
+	atExit(inputs.Cleanup)
+
+	// Forma posicional: el directorio de salidas; las entradas solo con --inputs explícito
+	if fs.NArg() > 0 {
+		*outDir = fs.Arg(0)
+		fs.Parse(fs.Args()[1:])
+		inputsSet := false
+		fs.Visit(func(f *flag.Flag) {
+			inputsSet = inputsSet || f.Name == "inputs"
+		})
+		if !inputsSet {
+			*checkInputs = false
+		}
+	}
+
+	mapsDir := inputs.MappingsDir(*dataDir, *mappingsDir)
+
+	var scale *validation.RatingScale
+	if *ratingScale != "none" {
+		s, err := validation.ParseRatingScale(*ratingScale)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error: --rating-scale:", err)
+			exit(1)
+		}
+		scale = &s
+	}
+
+	if !validation.ValidPolicy(*orphans) {
+		fmt.Fprintln(os.Stderr, "error: --orphans debe ser keep, drop o fail")
+		exit(1)
+	}
+
+	var reports []*validation.IntegrityReport
+	var dups *validation.DuplicateReport
+	var schema *validation.IntegrityReport
+	if *checkInputs {
+		fmt.Println("=== Integridad referencial de entradas:", *dataDir, "===")
+		rep := validation.CheckInputs(validation.InputFiles{
+			Movies:       inputs.Resolve(*dataDir, *moviesFile),
+			Ratings:      inputs.Resolve(*dataDir, *ratingsFile),
+			Links:        inputs.Resolve(*dataDir, *linksFile),
+			Tags:         inputs.Resolve(*dataDir, *tagsFile),
+			GenomeTags:   inputs.Resolve(*dataDir, *genomeTagsFile),
+			GenomeScores: inputs.Resolve(*dataDir, *genomeScoresFile),
+			ItemMap:      inputs.Resolve(mapsDir, *itemMapFile),
+			UserMap:      inputs.Resolve(mapsDir, *userMapFile),
+			Similarities: inputs.Resolve(mapsDir, *similaritiesFile),
+		}, *samples)
+		printLines(rep.Lines())
+		reports = append(reports, rep)
+
+		fmt.Println()
+		fmt.Println("=== Duplicados en entradas:", *dataDir, "===")
+		dups, _, _ = validation.CheckDuplicates(validation.InputFiles{
+			Movies:       inputs.Resolve(*dataDir, *moviesFile),
+			Ratings:      inputs.Resolve(*dataDir, *ratingsFile),
+			Links:        inputs.Resolve(*dataDir, *linksFile),
+			GenomeTags:   inputs.Resolve(*dataDir, *genomeTagsFile),
+			GenomeScores: inputs.Resolve(*dataDir, *genomeScoresFile),
+		}, *duplicates, *samples, "")
+		printLines(dups.Lines())
+	}
+	if *outDir != "" {
+		fmt.Println()
+		fmt.Println("=== Integridad referencial de salidas:", *outDir, "===")
+		rep := validation.CheckOutputs(*outDir, *samples)
+		printLines(rep.Lines())
+		reports = append(reports, rep)
+
+		fmt.Println()
+		fmt.Println("=== Esquema e invariantes de salidas:", *outDir, "===")
+		schema = validation.CheckSchema(*outDir, validation.SchemaOptions{
+			Scale:   scale,
+			ItemMap: inputs.Resolve(mapsDir, *itemMapFile),
+			UserMap: inputs.Resolve(mapsDir, *userMapFile),
+		}, *samples)
+		printLines(schema.Lines())
+	}
+
+	if *jsonOut != "" {
+		all := reports
+		if schema != nil {
+			all = append(all, schema)
+		}
+		if err := writeIntegrityJSON(*jsonOut, all); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo guardar %s: %v\n", *jsonOut, err)
+		} else {
+			fmt.Printf("\n  ✓ Reporte JSON guardado en %s\n", *jsonOut)
+		}
+	}
+
+	orphanCount := 0
+	for _, rep := range reports {
+		orphanCount += rep.Errors()
+	}
+	dupCount, schemaCount := 0, 0
+	if dups != nil {
+		dupCount = dups.Total()
+	}
+	if schema != nil {
+		schemaCount = schema.Errors()
+	}
+
+	// Resumen para CI: las violaciones de esquema siempre fallan; huérfanos y duplicados según su política
+	fmt.Println()
+	fmt.Println("=== Resumen ===")
+	failed := false
+	summary := func(label string, count int, fails bool) {
+		if count == 0 {
+			fmt.Printf("  ✓ %s: 0\n", label)
+			return
+		}
+		mark := "⚠"
+		if fails {
+			mark, failed = "✗", true
+		}
+		fmt.Printf("  %s %s: %d\n", mark, label, count)
+	}
+	summary("Referencias huérfanas", orphanCount, *orphans == validation.PolicyFail)
+	if dups != nil {
+		summary("Filas duplicadas en entradas", dupCount, *duplicates == validation.DupFail)
+	}
+	if schema != nil {
+		summary("Violaciones de esquema e invariantes", schemaCount, true)
+	}
+	fmt.Println()
+	if failed {
+		fmt.Println("✗ FAIL")
+		exit(1)
+	}
+	fmt.Println("✓ PASS")
+}
+
+// writeIntegrityJSON guarda uno o más reportes de integridad en un único JSON
+func writeIntegrityJSON(path string, reports []*validation.IntegrityReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if len(reports) == 1 {
+		return reports[0].WriteJSON(path)
+	}
+	return validation.WriteReportsJSON(path, reports)
+}
+
+func printLines(lines []string) {
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
@@ -4,5 +4,6 @@ go 1.25.4
 
 require (
 	github.com/jaswdr/faker v1.19.1
+	github.com/klauspost/compress v1.18.0
 	golang.org/x/crypto v0.45.0
 )
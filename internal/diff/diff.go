@@ -0,0 +1,381 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"pc4_etl/internal/loaders"
+)
+
+// collectionKeys son las colecciones comparadas y los campos que identifican cada documento
+var collectionKeys = []struct {
+	name string
+	key  []string
+}{
+	{"movies", []string{"movieId"}},
+	{"ratings", []string{"userId", "movieId"}},
+	{"users", []string{"userId"}},
+	{"similarities", []string{"_id"}},
+	{"user_ratings", []string{"_id"}},
+}
+
+// FieldStats cuenta los documentos en los que un campo cambió, apareció o desapareció
+type FieldStats struct {
+	Field   string `json:"field"`
+	Changed int    `json:"changed"`
+	Added   int    `json:"added"`   // presente solo en la nueva salida
+	Removed int    `json:"removed"` // presente solo en la salida anterior
+}
+
+// CollectionDiff es la comparación de una colección entre dos salidas
+type CollectionDiff struct {
+	Name           string        `json:"name"`
+	Key            string        `json:"key"`
+	Old            int           `json:"old"`
+	New            int           `json:"new"`
+	Added          int           `json:"added"`
+	Removed        int           `json:"removed"`
+	Changed        int           `json:"changed"`
+	Unchanged      int           `json:"unchanged"`
+	FieldsSampled  int           `json:"fieldsSampled,omitempty"` // modificados comparados campo por campo (hasta fieldDiffLimit)
+	Fields         []*FieldStats `json:"fields,omitempty"`
+	Samples        []string      `json:"samples,omitempty"`
+	AddedSamples   []string      `json:"addedSamples,omitempty"`
+	RemovedSamples []string      `json:"removedSamples,omitempty"`
+	Skipped        string        `json:"skipped,omitempty"`
+}
+
+// Report es la comparación completa entre dos directorios de salida
+type Report struct {
+	Old         string            `json:"old"`
+	New         string            `json:"new"`
+	Ignored     []string          `json:"ignored,omitempty"`
+	Collections []*CollectionDiff `json:"collections"`
+}
+
+// Compare compara los NDJSON de oldDir y newDir documento por documento (por clave), ignorando
+// los campos indicados (ej: createdAt, updatedAt) y guardando hasta samples ejemplos por colección
+func Compare(oldDir, newDir string, ignore []string, samples int) (*Report, error) {
+	rep := &Report{Old: oldDir, New: newDir, Ignored: ignore}
+	ignored := make(map[string]bool, len(ignore))
+	for _, f := range ignore {
+		ignored[f] = true
+	}
+	for _, c := range collectionKeys {
+		file := c.name + ".ndjson"
+		oldPath, newPath := filepath.Join(oldDir, file), filepath.Join(newDir, file)
+		oldExists, newExists := exists(oldPath), exists(newPath)
+		cd := &CollectionDiff{Name: c.name, Key: strings.Join(c.key, ",")}
+		switch {
+		case !oldExists && !newExists:
+			continue
+		case !oldExists:
+			cd.Skipped = "no existe en " + oldDir
+		case !newExists:
+			cd.Skipped = "no existe en " + newDir
+		}
+		rep.Collections = append(rep.Collections, cd)
+		if err := compareCollection(cd, c.key, oldPath, newPath, oldExists, newExists, ignored, samples); err != nil {
+			return rep, fmt.Errorf("%s: %w", c.name, err)
+		}
+	}
+	return rep, nil
+}
+
+// fieldDiffLimit es la cantidad máxima de documentos modificados cuyos campos se comparan (los
+// primeros de la salida nueva): acota la memoria y mantiene fija la cantidad de lecturas aunque
+// cambie casi toda la colección (ej: un reescalado de ratings)
+const fieldDiffLimit = 50000
+
+// compareCollection compara sin mantener ambas salidas en memoria y con un número fijo de lecturas:
+// hash de clave -> hash de documento de la anterior, comparación con la nueva (marcando hasta
+// fieldDiffLimit claves modificadas), una segunda lectura de la anterior para los ejemplos de
+// eliminados y los documentos marcados, y una de la nueva para las diferencias por campo
+func compareCollection(cd *CollectionDiff, key []string, oldPath, newPath string, oldExists, newExists bool, ignored map[string]bool, samples int) error {
+	type oldEntry struct {
+		hash uint64
+		seen bool // la clave aparece en la salida nueva
+	}
+	oldHashes := make(map[uint64]*oldEntry)
+	if oldExists {
+		err := readDocs(oldPath, key, ignored, func(k string, doc map[string]interface{}, h uint64) {
+			cd.Old++
+			oldHashes[keyHash(k)] = &oldEntry{hash: h}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	changed := make(map[uint64]bool)
+	if newExists {
+		err := readDocs(newPath, key, ignored, func(k string, doc map[string]interface{}, h uint64) {
+			cd.New++
+			kh := keyHash(k)
+			old, ok := oldHashes[kh]
+			if ok {
+				old.seen = true
+			}
+			switch {
+			case !ok:
+				cd.Added++
+				if len(cd.AddedSamples) < samples {
+					cd.AddedSamples = append(cd.AddedSamples, k)
+				}
+			case old.hash != h:
+				cd.Changed++
+				if len(changed) < fieldDiffLimit {
+					changed[kh] = true
+				}
+			default:
+				cd.Unchanged++
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range oldHashes {
+		if !e.seen {
+			cd.Removed++
+		}
+	}
+	cd.FieldsSampled = len(changed)
+	if cd.Removed == 0 && len(changed) == 0 {
+		return nil
+	}
+
+	// Segunda lectura de la anterior: ejemplos de eliminados (las claves menores) y documentos
+	// modificados a comparar
+	oldDocs := make(map[string]map[string]interface{}, len(changed))
+	err := readDocs(oldPath, key, ignored, func(k string, doc map[string]interface{}, h uint64) {
+		kh := keyHash(k)
+		if e := oldHashes[kh]; e != nil && !e.seen {
+			cd.RemovedSamples = insertSorted(cd.RemovedSamples, k, samples)
+		}
+		if changed[kh] {
+			oldDocs[k] = doc
+		}
+	})
+	if err != nil {
+		return err
+	}
+	oldHashes = nil
+	if len(oldDocs) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]*FieldStats)
+	diffDoc := func(k string, oldDoc, newDoc map[string]interface{}) {
+		var parts []string
+		compareValues("", oldDoc, newDoc, func(field, change string, oldV, newV interface{}) {
+			fs := fields[field]
+			if fs == nil {
+				fs = &FieldStats{Field: field}
+				fields[field] = fs
+			}
+			switch change {
+			case "changed":
+				fs.Changed++
+				parts = append(parts, fmt.Sprintf("%s: %s -> %s", field, short(oldV), short(newV)))
+			case "added":
+				fs.Added++
+				parts = append(parts, fmt.Sprintf("%s: (ausente) -> %s", field, short(newV)))
+			case "removed":
+				fs.Removed++
+				parts = append(parts, fmt.Sprintf("%s: %s -> (ausente)", field, short(oldV)))
+			}
+		})
+		if len(cd.Samples) < samples && len(parts) > 0 {
+			sort.Strings(parts)
+			cd.Samples = append(cd.Samples, k+": "+strings.Join(parts, "; "))
+		}
+	}
+
+	err = readDocs(newPath, key, ignored, func(k string, doc map[string]interface{}, h uint64) {
+		if oldDoc, ok := oldDocs[k]; ok {
+			diffDoc(k, oldDoc, doc)
+			delete(oldDocs, k)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, fs := range fields {
+		cd.Fields = append(cd.Fields, fs)
+	}
+	sort.Slice(cd.Fields, func(i, j int) bool {
+		a, b := cd.Fields[i], cd.Fields[j]
+		if ta, tb := a.Changed+a.Added+a.Removed, b.Changed+b.Added+b.Removed; ta != tb {
+			return ta > tb
+		}
+		return a.Field < b.Field
+	})
+	return nil
+}
+
+// compareValues recorre dos valores JSON en paralelo: los objetos se comparan campo por campo
+// (ratingStats.average) y el resto (números, textos, arreglos) como un todo
+func compareValues(path string, oldV, newV interface{}, report func(field, change string, oldV, newV interface{})) {
+	oldMap, oldIsMap := oldV.(map[string]interface{})
+	newMap, newIsMap := newV.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := make(map[string]bool)
+		for k := range oldMap {
+			keys[k] = true
+		}
+		for k := range newMap {
+			keys[k] = true
+		}
+		for k := range keys {
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+			o, inOld := oldMap[k]
+			n, inNew := newMap[k]
+			switch {
+			case !inOld:
+				report(field, "added", nil, n)
+			case !inNew:
+				report(field, "removed", o, nil)
+			default:
+				compareValues(field, o, n, report)
+			}
+		}
+		return
+	}
+	ob, _ := json.Marshal(oldV)
+	nb, _ := json.Marshal(newV)
+	if !bytes.Equal(ob, nb) {
+		report(path, "changed", oldV, newV)
+	}
+}
+
+// keyHash resume la clave de un documento para no guardar una cadena por documento
+func keyHash(k string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(k))
+	return h.Sum64()
+}
+
+// insertSorted agrega s a la lista ordenada (sin repetir) conservando solo las limit menores
+func insertSorted(list []string, s string, limit int) []string {
+	i := sort.SearchStrings(list, s)
+	if i >= limit || (i < len(list) && list[i] == s) {
+		return list
+	}
+	list = append(list, "")
+	copy(list[i+1:], list[i:])
+	list[i] = s
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// readDocs lee un NDJSON y llama a fn con la clave, el documento (sin los campos ignorados) y su hash
+func readDocs(path string, key []string, ignored map[string]bool, fn func(k string, doc map[string]interface{}, h uint64)) error {
+	return loaders.ReadNDJSON(path, func(line []byte) error {
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return err
+		}
+		parts := make([]string, len(key))
+		for i, f := range key {
+			parts[i] = fmt.Sprintf("%s=%v", f, doc[f])
+		}
+		for f := range ignored {
+			delete(doc, f)
+		}
+		// json.Marshal ordena las claves de los mapas, por lo que el hash no depende del orden
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		h := fnv.New64a()
+		h.Write(b)
+		fn(strings.Join(parts, " "), doc, h.Sum64())
+		return nil
+	})
+}
+
+// short resume un valor para los ejemplos
+func short(v interface{}) string {
+	b, _ := json.Marshal(v)
+	s := string(b)
+	if len(s) > 60 {
+		s = s[:57] + "..."
+	}
+	return s
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// Changes devuelve el total de documentos agregados, eliminados o modificados
+func (r *Report) Changes() int {
+	total := 0
+	for _, c := range r.Collections {
+		total += c.Added + c.Removed + c.Changed
+	}
+	return total
+}
+
+// Lines devuelve el resumen legible
+func (r *Report) Lines() []string {
+	var lines []string
+	for _, c := range r.Collections {
+		header := fmt.Sprintf("%s (%s): %d -> %d documentos | +%d agregados, -%d eliminados, ~%d modificados, =%d sin cambios",
+			c.Name, c.Key, c.Old, c.New, c.Added, c.Removed, c.Changed, c.Unchanged)
+		if c.Skipped != "" {
+			header += " (" + c.Skipped + ")"
+		}
+		lines = append(lines, header)
+		if c.FieldsSampled < c.Changed {
+			lines = append(lines, fmt.Sprintf("  • campos comparados en %d de %d modificados", c.FieldsSampled, c.Changed))
+		}
+		for _, f := range c.Fields {
+			if f.Changed > 0 {
+				lines = append(lines, fmt.Sprintf("  • %s cambió en %d", f.Field, f.Changed))
+			}
+			if f.Added > 0 {
+				lines = append(lines, fmt.Sprintf("  • %s nuevo en %d", f.Field, f.Added))
+			}
+			if f.Removed > 0 {
+				lines = append(lines, fmt.Sprintf("  • %s eliminado en %d", f.Field, f.Removed))
+			}
+		}
+		for _, s := range c.AddedSamples {
+			lines = append(lines, "    + "+s)
+		}
+		for _, s := range c.RemovedSamples {
+			lines = append(lines, "    - "+s)
+		}
+		for _, s := range c.Samples {
+			lines = append(lines, "    ~ "+s)
+		}
+	}
+	return lines
+}
+
+// WriteJSON guarda el reporte completo en formato JSON
+func (r *Report) WriteJSON(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,258 @@
+package inputs
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// columnAliases son nombres alternativos aceptados para columnas lógicas ("archivo.columna")
+var columnAliases = map[string][]string{
+	"similarities.iIdx":        {"i", "itemIdx"},
+	"similarities.neighborIdx": {"jIdx", "j", "neighbor"},
+	"similarities.similarity":  {"sim", "score"},
+}
+
+var (
+	overridesMu sync.RWMutex
+	overrides   = make(map[string]string) // "ratings.movieId" -> "item_id"
+	delimiters  = make(map[string]rune)   // "source-ratings" -> ';'
+)
+
+// SetColumnOverride registra un override con formato "archivo.columna=nombre_en_cabecera"
+// (ej: "ratings.movieId=item_id")
+func SetColumnOverride(spec string) error {
+	key, header, ok := strings.Cut(spec, "=")
+	key, header = strings.TrimSpace(key), strings.TrimSpace(header)
+	if !ok || header == "" || !strings.Contains(key, ".") {
+		return fmt.Errorf("override de columna inválido %q (formato: archivo.columna=nombre)", spec)
+	}
+	overridesMu.Lock()
+	overrides[key] = header
+	overridesMu.Unlock()
+	return nil
+}
+
+// SetDelimiter registra el separador de campos para los archivos de tipo kind (default ',')
+func SetDelimiter(kind string, comma rune) {
+	overridesMu.Lock()
+	delimiters[kind] = comma
+	overridesMu.Unlock()
+}
+
+// ColumnOverrides implementa flag.Value para --column (repetible)
+type ColumnOverrides []string
+
+func (c *ColumnOverrides) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *ColumnOverrides) Set(spec string) error {
+	if err := SetColumnOverride(spec); err != nil {
+		return err
+	}
+	*c = append(*c, spec)
+	return nil
+}
+
+// CSVFile es un CSV de entrada con cabecera cuyas columnas se resuelven por nombre.
+// Si la ruta abarca varios archivos (glob o directorio) se leen en orden como uno solo.
+type CSVFile struct {
+	*csv.Reader
+	rc     io.ReadCloser
+	kind   string
+	header []string
+	rest   []string // archivos pendientes de una entrada particionada
+	remap  []int    // columna del archivo actual para cada columna de header (nil = mismo orden)
+}
+
+// OpenCSV abre el archivo (ver Open), lee la cabecera y verifica que existan las columnas
+// requeridas. kind identifica el archivo para los overrides (ej: "ratings", "links").
+// En entradas particionadas se descarta la cabecera de cada archivo siguiente y, si sus
+// columnas están en otro orden, los registros se reordenan según la primera cabecera.
+func OpenCSV(path, kind string, required ...string) (*CSVFile, error) {
+	files, err := Expand(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &CSVFile{kind: kind, rest: files}
+	header, err := c.advance()
+	if err != nil {
+		return nil, err
+	}
+	c.header = header
+
+	var missing []string
+	for _, name := range required {
+		if c.Index(name) < 0 {
+			missing = append(missing, c.describe(name))
+		}
+	}
+	if len(missing) > 0 {
+		c.Close()
+		return nil, fmt.Errorf("%s: columnas no encontradas en la cabecera %v: %s (use --column %s.<columna>=<nombre>)",
+			path, header, strings.Join(missing, ", "), kind)
+	}
+	return c, nil
+}
+
+// advance abre el siguiente archivo pendiente y devuelve su cabecera (omite archivos vacíos)
+func (c *CSVFile) advance() ([]string, error) {
+	for len(c.rest) > 0 {
+		if c.rc != nil {
+			c.rc.Close()
+			c.rc = nil
+		}
+		p := c.rest[0]
+		c.rest = c.rest[1:]
+		rc, err := openFile(p)
+		if err != nil {
+			return nil, err
+		}
+		// Delimitador: el del tipo de archivo (SetDelimiter), el global (SetDefaultDelimiter) o detectado
+		br := bufio.NewReaderSize(rc, sniffSize)
+		r := csv.NewReader(br)
+		r.FieldsPerRecord = -1
+		overridesMu.RLock()
+		kindComma, hasKindComma := delimiters[c.kind]
+		overridesMu.RUnlock()
+		settingsMu.RLock()
+		comma := defaultDelimiter
+		settingsMu.RUnlock()
+		switch {
+		case hasKindComma:
+			r.Comma, r.LazyQuotes = kindComma, true
+		case comma != 0:
+			r.Comma = comma
+		default:
+			r.Comma = sniffDelimiter(br)
+		}
+		recordDelimiter(p, r.Comma)
+		c.Reader, c.rc = r, rc
+
+		header, err := r.Read()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			rc.Close()
+			c.rc = nil
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		return header, nil
+	}
+	return nil, io.EOF
+}
+
+// Read devuelve el siguiente registro, pasando al próximo archivo de la entrada al terminar el actual
+func (c *CSVFile) Read() ([]string, error) {
+	for {
+		rec, err := c.Reader.Read()
+		if err == io.EOF && len(c.rest) > 0 {
+			header, aerr := c.advance()
+			if aerr == io.EOF {
+				return nil, io.EOF
+			}
+			if aerr != nil {
+				return nil, aerr
+			}
+			c.setRemap(header)
+			continue
+		}
+		if err != nil || c.remap == nil {
+			return rec, err
+		}
+		out := make([]string, len(c.remap))
+		for i, j := range c.remap {
+			out[i] = Field(rec, j)
+		}
+		return out, nil
+	}
+}
+
+// setRemap compara la cabecera de un archivo siguiente con la principal
+func (c *CSVFile) setRemap(header []string) {
+	same := len(header) == len(c.header)
+	for i := 0; same && i < len(header); i++ {
+		same = strings.TrimSpace(header[i]) == strings.TrimSpace(c.header[i])
+	}
+	if same {
+		c.remap = nil
+		return
+	}
+	c.remap = make([]int, len(c.header))
+	for i, name := range c.header {
+		c.remap[i] = findHeader(header, strings.TrimSpace(name))
+	}
+}
+
+// Index devuelve la posición de la columna lógica en la cabecera, o -1 si no existe
+func (c *CSVFile) Index(name string) int {
+	key := c.kind + "." + name
+
+	overridesMu.RLock()
+	override, hasOverride := overrides[key]
+	overridesMu.RUnlock()
+	if hasOverride {
+		return findHeader(c.header, override)
+	}
+
+	if i := findHeader(c.header, name); i >= 0 {
+		return i
+	}
+	for _, alias := range columnAliases[key] {
+		if i := findHeader(c.header, alias); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Header devuelve la cabecera leída
+func (c *CSVFile) Header() []string {
+	return c.header
+}
+
+// Close cierra el archivo subyacente
+func (c *CSVFile) Close() error {
+	if c.rc == nil {
+		return nil
+	}
+	return c.rc.Close()
+}
+
+// describe devuelve el nombre esperado de la columna (incluyendo override) para mensajes de error
+func (c *CSVFile) describe(name string) string {
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	if override, ok := overrides[c.kind+"."+name]; ok {
+		return fmt.Sprintf("%s (override %q)", name, override)
+	}
+	return name
+}
+
+// findHeader busca el nombre exacto y luego sin distinguir mayúsculas ni espacios
+func findHeader(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Field devuelve el valor de la columna i del registro ("" si i < 0 o el registro es más corto)
+func Field(rec []string, i int) string {
+	if i < 0 || i >= len(rec) {
+		return ""
+	}
+	return rec[i]
+}
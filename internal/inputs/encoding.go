@@ -0,0 +1,305 @@
+package inputs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Codificaciones de entrada soportadas
+const (
+	EncodingAuto        = "auto"         // UTF-8; los bytes inválidos se interpretan como Windows-1252
+	EncodingUTF8        = "utf-8"        // sin transcodificar
+	EncodingLatin1      = "latin-1"      // ISO-8859-1
+	EncodingWindows1252 = "windows-1252" // Latin-1 con comillas tipográficas, €, etc. en 0x80-0x9F
+	EncodingUTF16LE     = "utf-16le"     // solo por BOM
+	EncodingUTF16BE     = "utf-16be"     // solo por BOM
+)
+
+// sniffSize es el tamaño de la muestra usada para detectar codificación y delimitador
+const sniffSize = 64 * 1024
+
+// delimiterCandidates son los separadores que se prueban sobre la cabecera
+var delimiterCandidates = []rune{',', ';', '\t', '|'}
+
+// cp1252 mapea los bytes 0x80-0x9F de Windows-1252 (el resto coincide con Latin-1)
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// Detection registra cómo se leyó un archivo de entrada
+type Detection struct {
+	Path      string
+	Encoding  string
+	BOM       bool
+	Delimiter string // vacío si el archivo no se leyó como CSV
+}
+
+var (
+	settingsMu       sync.RWMutex
+	encoding         = EncodingAuto
+	defaultDelimiter rune // 0 = detectar
+	detections       = make(map[string]*Detection)
+	detectionOrder   []string
+)
+
+// SetEncoding fija la codificación de todas las entradas (auto, utf-8, latin-1, windows-1252)
+func SetEncoding(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "latin1", "iso-8859-1":
+		name = EncodingLatin1
+	case "utf8":
+		name = EncodingUTF8
+	case "cp1252":
+		name = EncodingWindows1252
+	}
+	switch name {
+	case EncodingAuto, EncodingUTF8, EncodingLatin1, EncodingWindows1252:
+	default:
+		return fmt.Errorf("codificación desconocida %q (auto, utf-8, latin-1, windows-1252)", name)
+	}
+	settingsMu.Lock()
+	encoding = name
+	settingsMu.Unlock()
+	return nil
+}
+
+// SetDefaultDelimiter fija el separador de todos los CSV ("auto" para detectarlo; "tab" o "\t" para tabulador)
+func SetDefaultDelimiter(spec string) error {
+	var comma rune
+	switch spec {
+	case "auto", "":
+	case "tab", "\\t", "\t":
+		comma = '\t'
+	default:
+		r := []rune(spec)
+		if len(r) != 1 || r[0] == '"' || r[0] == '\n' || r[0] == '\r' {
+			return fmt.Errorf("delimitador inválido %q (un carácter, \"tab\" o \"auto\")", spec)
+		}
+		comma = r[0]
+	}
+	settingsMu.Lock()
+	defaultDelimiter = comma
+	settingsMu.Unlock()
+	return nil
+}
+
+// Detections devuelve la configuración detectada para cada archivo leído, en orden de apertura
+func Detections() []Detection {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	out := make([]Detection, 0, len(detectionOrder))
+	for _, p := range detectionOrder {
+		out = append(out, *detections[p])
+	}
+	return out
+}
+
+// record guarda la detección de un archivo (reabrirlo la reemplaza, conservando el delimitador)
+func record(d Detection) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	if prev, ok := detections[d.Path]; ok {
+		d.Delimiter = prev.Delimiter
+		*prev = d
+		return
+	}
+	detectionOrder = append(detectionOrder, d.Path)
+	detections[d.Path] = &d
+}
+
+// recordDelimiter agrega el delimitador usado a la detección del archivo
+func recordDelimiter(path string, comma rune) {
+	name := string(comma)
+	if comma == '\t' {
+		name = "tab"
+	}
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	if d, ok := detections[path]; ok {
+		d.Delimiter = name
+	}
+}
+
+// decodeText quita el BOM y transcodifica a UTF-8 según la codificación configurada o detectada
+func decodeText(rc io.ReadCloser, path string) io.ReadCloser {
+	settingsMu.RLock()
+	mode := encoding
+	settingsMu.RUnlock()
+
+	br := bufio.NewReaderSize(rc, sniffSize)
+	sample, _ := br.Peek(sniffSize)
+
+	det := Detection{Path: path}
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		br.Discard(3)
+		sample = sample[3:]
+		det.BOM = true
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		br.Discard(2)
+		det.BOM, mode = true, EncodingUTF16LE
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		br.Discard(2)
+		det.BOM, mode = true, EncodingUTF16BE
+	}
+
+	det.Encoding = mode
+	if mode == EncodingAuto {
+		det.Encoding = EncodingUTF8
+		if !validUTF8Prefix(sample) {
+			det.Encoding = EncodingWindows1252
+		}
+	}
+	record(det)
+
+	if mode == EncodingUTF8 {
+		return &multiCloser{Reader: br, closers: []io.Closer{rc}}
+	}
+	return &multiCloser{Reader: &textDecoder{br: br, mode: mode}, closers: []io.Closer{rc}}
+}
+
+// validUTF8Prefix indica si la muestra es UTF-8 válido (ignorando una secuencia cortada al final)
+func validUTF8Prefix(b []byte) bool {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				b = b[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(b)
+}
+
+// textDecoder convierte a UTF-8 un texto en Latin-1, Windows-1252, UTF-16 o UTF-8 mixto (auto)
+type textDecoder struct {
+	br   *bufio.Reader
+	mode string
+	out  []byte
+	err  error
+}
+
+func (d *textDecoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.fill()
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// fill decodifica el contenido disponible del buffer (sin cortar secuencias multibyte)
+func (d *textDecoder) fill() {
+	buf, err := d.br.Peek(d.br.Size())
+	if len(buf) == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		d.err = err
+		return
+	}
+	atEOF := err != nil
+
+	out := make([]byte, 0, len(buf)+len(buf)/2)
+	i := 0
+	switch d.mode {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		for i+1 < len(buf) {
+			u := uint16(buf[i])<<8 | uint16(buf[i+1])
+			if d.mode == EncodingUTF16LE {
+				u = uint16(buf[i+1])<<8 | uint16(buf[i])
+			}
+			if utf16.IsSurrogate(rune(u)) {
+				if i+3 >= len(buf) {
+					if !atEOF {
+						break
+					}
+					out = utf8.AppendRune(out, utf8.RuneError)
+					i += 2
+					continue
+				}
+				u2 := uint16(buf[i+2])<<8 | uint16(buf[i+3])
+				if d.mode == EncodingUTF16LE {
+					u2 = uint16(buf[i+3])<<8 | uint16(buf[i+2])
+				}
+				out = utf8.AppendRune(out, utf16.DecodeRune(rune(u), rune(u2)))
+				i += 4
+				continue
+			}
+			out = utf8.AppendRune(out, rune(u))
+			i += 2
+		}
+		if atEOF && i < len(buf) {
+			i = len(buf) // byte impar final
+		}
+	default:
+		for i < len(buf) {
+			c := buf[i]
+			if c < utf8.RuneSelf {
+				out = append(out, c)
+				i++
+				continue
+			}
+			if d.mode == EncodingAuto {
+				r, size := utf8.DecodeRune(buf[i:])
+				if r != utf8.RuneError || size > 1 {
+					out = append(out, buf[i:i+size]...)
+					i += size
+					continue
+				}
+				if !utf8.FullRune(buf[i:]) && !atEOF {
+					break // secuencia cortada por el borde del buffer
+				}
+			}
+			out = utf8.AppendRune(out, decodeByte(c, d.mode))
+			i++
+		}
+	}
+	d.br.Discard(i)
+	d.out = out
+}
+
+// decodeByte interpreta un byte >= 0x80 como Latin-1 o Windows-1252
+func decodeByte(c byte, mode string) rune {
+	if c >= 0x80 && c <= 0x9F && mode != EncodingLatin1 {
+		return cp1252[c-0x80]
+	}
+	return rune(c)
+}
+
+// sniffDelimiter elige el separador más frecuente fuera de comillas en la primera línea
+func sniffDelimiter(br *bufio.Reader) rune {
+	sample, _ := br.Peek(sniffSize)
+	if i := bytes.IndexByte(sample, '\n'); i >= 0 {
+		sample = sample[:i]
+	}
+	counts := make(map[rune]int)
+	inQuotes := false
+	for _, r := range string(sample) {
+		if r == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if !inQuotes {
+			counts[r]++
+		}
+	}
+	best, bestCount := ',', 0
+	for _, c := range delimiterCandidates {
+		if counts[c] > bestCount {
+			best, bestCount = c, counts[c]
+		}
+	}
+	return best
+}
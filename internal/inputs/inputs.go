@@ -0,0 +1,333 @@
+package inputs
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressedExts son las extensiones comprimidas que se prueban si el archivo plano no existe
+var compressedExts = []string{".gz", ".zst"}
+
+// Stdin es el nombre de entrada que representa la entrada estándar
+const Stdin = "-"
+
+var (
+	stdinOnce  sync.Once
+	stdinSpool string
+	stdinErr   error
+)
+
+// Resolve une el nombre de un archivo de entrada con el directorio de datos, respetando
+// "-" (stdin) y rutas absolutas
+func Resolve(dir, name string) string {
+	if name == Stdin || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// Expand devuelve, en orden, los archivos que representa p: "-" (stdin), un patrón glob
+// (ej: ratings/2025-*.csv), un directorio (sus archivos no ocultos) o un único archivo
+func Expand(p string) ([]string, error) {
+	if p == Stdin {
+		return []string{p}, nil
+	}
+	if _, _, ok := splitZipPath(p); ok {
+		return []string{p}, nil
+	}
+
+	if strings.ContainsAny(p, "*?[") {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && !info.IsDir() {
+				files = append(files, m)
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%s: ningún archivo coincide con el patrón: %w", p, os.ErrNotExist)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	info, err := os.Stat(p)
+	if err != nil || !info.IsDir() {
+		return []string{p}, nil
+	}
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		files = append(files, filepath.Join(p, e.Name()))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: directorio sin archivos: %w", p, os.ErrNotExist)
+	}
+	return files, nil
+}
+
+// Open abre un archivo de entrada de forma transparente:
+//   - rutas dentro de un .zip (ej: data/ml-25m.zip/movies.csv), buscando el archivo
+//     también dentro de la carpeta raíz del zip (ml-25m/movies.csv)
+//   - archivos .gz y .zst, descomprimidos al vuelo
+//   - si "x.csv" no existe pero sí "x.csv.gz" o "x.csv.zst", se usa el comprimido
+//   - patrones glob y directorios (ver Expand), concatenados en orden; para CSV con
+//     cabecera usar OpenCSV, que descarta las cabeceras repetidas
+//   - "-" para stdin
+func Open(p string) (io.ReadCloser, error) {
+	files, err := Expand(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 1 {
+		return openFile(files[0])
+	}
+	return &concatReader{paths: files}, nil
+}
+
+// openFile abre un único archivo como texto UTF-8 sin BOM (ver decodeText)
+func openFile(p string) (io.ReadCloser, error) {
+	rc, err := openRaw(p)
+	if err != nil {
+		return nil, err
+	}
+	return decodeText(rc, p), nil
+}
+
+// openRaw abre un único archivo (zip, comprimido o stdin) sin transcodificar
+func openRaw(p string) (io.ReadCloser, error) {
+	if p == Stdin {
+		return openStdin()
+	}
+	if archive, inner, ok := splitZipPath(p); ok {
+		return openFromZip(archive, inner)
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			for _, ext := range compressedExts {
+				if cf, cerr := os.Open(p + ext); cerr == nil {
+					return decompress(cf, p+ext)
+				}
+			}
+		}
+		return nil, err
+	}
+	return decompress(f, p)
+}
+
+// openStdin copia stdin a un archivo temporal la primera vez, ya que varias etapas leen la misma
+// entrada (ej: LoadRatingStats y ProcessRatings); las aperturas siguientes reutilizan la copia
+func openStdin() (io.ReadCloser, error) {
+	stdinOnce.Do(func() {
+		f, err := os.CreateTemp("", "etl-stdin-")
+		if err != nil {
+			stdinErr = err
+			return
+		}
+		defer f.Close()
+		if _, err := io.Copy(f, os.Stdin); err != nil {
+			stdinErr = err
+			return
+		}
+		stdinSpool = f.Name()
+	})
+	if stdinErr != nil {
+		return nil, stdinErr
+	}
+	f, err := os.Open(stdinSpool)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Cleanup elimina la copia temporal de stdin (si se usó)
+func Cleanup() {
+	if stdinSpool != "" {
+		os.Remove(stdinSpool)
+	}
+}
+
+// concatReader lee varios archivos en secuencia, abriéndolos de a uno y agregando un salto de
+// línea entre archivos cuando el anterior no termina en uno
+type concatReader struct {
+	paths []string
+	cur   io.ReadCloser
+	last  byte
+	pad   bool
+}
+
+func (c *concatReader) Read(b []byte) (int, error) {
+	for {
+		if c.pad {
+			c.pad = false
+			if c.last != '\n' && c.last != 0 && len(b) > 0 {
+				b[0], c.last = '\n', '\n'
+				return 1, nil
+			}
+		}
+		if c.cur == nil {
+			if len(c.paths) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := openFile(c.paths[0])
+			if err != nil {
+				return 0, err
+			}
+			c.cur, c.paths = rc, c.paths[1:]
+		}
+		n, err := c.cur.Read(b)
+		if n > 0 {
+			c.last = b[n-1]
+			return n, nil
+		}
+		if err == io.EOF {
+			c.cur.Close()
+			c.cur, c.pad = nil, true
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (c *concatReader) Close() error {
+	if c.cur != nil {
+		return c.cur.Close()
+	}
+	return nil
+}
+
+// Exists indica si Open encontraría el archivo (incluyendo zip y variantes comprimidas)
+func Exists(p string) bool {
+	rc, err := Open(p)
+	if err != nil {
+		return false
+	}
+	rc.Close()
+	return true
+}
+
+// MappingsDir devuelve el directorio de item_map.csv, user_map.csv y las similitudes, que se
+// actualizan con --update-mappings: dir si se indicó; si no, dataDir o, cuando dataDir es un .zip
+// (que no se puede escribir), el directorio que lo contiene
+func MappingsDir(dataDir, dir string) string {
+	if dir != "" {
+		return dir
+	}
+	if strings.EqualFold(filepath.Ext(dataDir), ".zip") {
+		return filepath.Dir(dataDir)
+	}
+	return dataDir
+}
+
+// splitZipPath separa "dir/archivo.zip/interno.csv" en ruta del zip y nombre interno
+func splitZipPath(p string) (string, string, bool) {
+	slashed := filepath.ToSlash(p)
+	lower := strings.ToLower(slashed)
+	i := strings.Index(lower, ".zip/")
+	if i < 0 {
+		return "", "", false
+	}
+	return filepath.FromSlash(slashed[:i+len(".zip")]), slashed[i+len(".zip/"):], true
+}
+
+// openFromZip abre un archivo dentro del zip (coincidencia exacta o dentro de una carpeta raíz)
+func openFromZip(archive, inner string) (io.ReadCloser, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{inner}
+	for _, ext := range compressedExts {
+		candidates = append(candidates, inner+ext)
+	}
+	for _, name := range candidates {
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() {
+				continue
+			}
+			if zf.Name == name || (path.Base(zf.Name) == path.Base(name) && strings.Count(zf.Name, "/") == strings.Count(name, "/")+1) {
+				rc, err := zf.Open()
+				if err != nil {
+					zr.Close()
+					return nil, err
+				}
+				return decompress(&multiCloser{Reader: rc, closers: []io.Closer{rc, zr}}, zf.Name)
+			}
+		}
+	}
+	zr.Close()
+	return nil, fmt.Errorf("%s no encontrado en %s: %w", inner, archive, os.ErrNotExist)
+}
+
+// decompress envuelve el reader según la extensión del nombre (.gz, .zst o sin compresión)
+func decompress(rc io.ReadCloser, name string) (io.ReadCloser, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz":
+		gz, err := gzip.NewReader(rc)
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+		return &multiCloser{Reader: gz, closers: []io.Closer{gz, rc}}, nil
+	case ".zst":
+		zr, err := zstd.NewReader(rc)
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+		return &multiCloser{Reader: zr, closers: []io.Closer{zstdCloser{zr}, rc}}, nil
+	default:
+		return rc, nil
+	}
+}
+
+// multiCloser cierra en orden todos los recursos asociados a un reader
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var firstErr error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// zstdCloser adapta zstd.Decoder (Close sin error) a io.Closer
+type zstdCloser struct {
+	d *zstd.Decoder
+}
+
+func (z zstdCloser) Close() error {
+	z.d.Close()
+	return nil
+}
@@ -0,0 +1,218 @@
+package loaders
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pc4_etl/internal/inputs"
+)
+
+// Formatos de dataset soportados
+const (
+	FormatAuto   = "auto"
+	FormatCSV    = "csv"     // ml-latest / ml-25m: CSV con cabecera
+	FormatML100K = "ml-100k" // u.data (tab), u.item y u.user (pipe)
+	FormatML1M   = "ml-1m"   // ratings.dat, movies.dat y users.dat separados por "::"
+	FormatNDJSON = "ndjson"  // salida previa del ETL o mongoexport (movies/users/ratings/similarities.ndjson)
+)
+
+// ml100kGenres es el orden de las columnas one-hot de género en u.item
+var ml100kGenres = []string{
+	"(no genres listed)", "Action", "Adventure", "Animation", "Children", "Comedy", "Crime",
+	"Documentary", "Drama", "Fantasy", "Film-Noir", "Horror", "Musical", "Mystery",
+	"Romance", "Sci-Fi", "Thriller", "War", "Western",
+}
+
+// ml1mOccupations son las ocupaciones de users.dat por código (README de ml-1m); ml-100k ya las
+// trae como texto en u.user
+var ml1mOccupations = []string{
+	"other", "academic/educator", "artist", "clerical/admin", "college/grad student",
+	"customer service", "doctor/health care", "executive/managerial", "farmer", "homemaker",
+	"K-12 student", "lawyer", "programmer", "retired", "sales/marketing", "scientist",
+	"self-employed", "technician/engineer", "tradesman/craftsman", "unemployed", "writer",
+}
+
+// DatasetFiles contiene las rutas de los CSV estándar generados por el adaptador
+type DatasetFiles struct {
+	Format  string
+	Movies  string
+	Ratings string
+	Users   string // demografía (userId,gender,age,occupation,zip); vacío si el dataset no la trae
+}
+
+// DetectDatasetFormat detecta el formato de MovieLens según los archivos presentes en dataDir
+func DetectDatasetFormat(dataDir string) string {
+	exists := func(name string) bool {
+		return inputs.Exists(filepath.Join(dataDir, name))
+	}
+	switch {
+	case exists("u.data") && exists("u.item"):
+		return FormatML100K
+	case exists("ratings.dat") && exists("movies.dat"):
+		return FormatML1M
+	case !exists("movies.csv") && !exists("ratings.csv") && (exists("movies.ndjson") || exists("ratings.ndjson")):
+		return FormatNDJSON
+	default:
+		return FormatCSV
+	}
+}
+
+// ConvertDataset convierte un dataset nativo (ml-100k o ml-1m) a movies.csv/ratings.csv/users.csv
+// con el layout de ml-latest en stagingDir, para alimentar los mismos loaders y procesadores;
+// users.csv lleva la demografía (u.user, users.dat) que ProcessUsers agrega a cada usuario
+func ConvertDataset(format, dataDir, stagingDir string) (*DatasetFiles, error) {
+	if format == FormatAuto {
+		format = DetectDatasetFormat(dataDir)
+	}
+	files := &DatasetFiles{
+		Format:  format,
+		Movies:  filepath.Join(stagingDir, "movies.csv"),
+		Ratings: filepath.Join(stagingDir, "ratings.csv"),
+		Users:   filepath.Join(stagingDir, "users.csv"),
+	}
+
+	if err := os.MkdirAll(stagingDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch format {
+	case FormatML100K:
+		if err = convertDelimited(filepath.Join(dataDir, "u.item"), files.Movies, "|", []string{"movieId", "title", "genres"}, ml100kMovie); err != nil {
+			return nil, err
+		}
+		if err = convertDelimited(filepath.Join(dataDir, "u.data"), files.Ratings, "\t", []string{"userId", "movieId", "rating", "timestamp"}, passFields(4)); err != nil {
+			return nil, err
+		}
+		// u.user: user id | age | gender | occupation | zip code
+		err = convertDelimited(filepath.Join(dataDir, "u.user"), files.Users, "|", []string{"userId", "gender", "age", "occupation", "zip"}, func(f []string) []string {
+			if len(f) < 5 {
+				return nil
+			}
+			return []string{f[0], f[2], f[1], f[3], f[4]}
+		})
+	case FormatML1M:
+		if err = convertDelimited(filepath.Join(dataDir, "movies.dat"), files.Movies, "::", []string{"movieId", "title", "genres"}, ml1mMovie); err != nil {
+			return nil, err
+		}
+		if err = convertDelimited(filepath.Join(dataDir, "ratings.dat"), files.Ratings, "::", []string{"userId", "movieId", "rating", "timestamp"}, passFields(4)); err != nil {
+			return nil, err
+		}
+		// users.dat: UserID::Gender::Age::Occupation::Zip-code (age es el código de rango: 1, 18, 25...)
+		err = convertDelimited(filepath.Join(dataDir, "users.dat"), files.Users, "::", []string{"userId", "gender", "age", "occupation", "zip"}, ml1mUser)
+	default:
+		return nil, fmt.Errorf("formato de dataset no convertible: %s", format)
+	}
+
+	// La demografía es opcional: si falta el archivo se continúa sin ella
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			files.Users = ""
+			return files, nil
+		}
+		return nil, err
+	}
+	return files, nil
+}
+
+// ml1mUser copia una fila de users.dat traduciendo el código de ocupación a su nombre
+func ml1mUser(f []string) []string {
+	if len(f) < 5 {
+		return nil
+	}
+	occupation := f[3]
+	if code, err := strconv.Atoi(strings.TrimSpace(occupation)); err == nil && code >= 0 && code < len(ml1mOccupations) {
+		occupation = ml1mOccupations[code]
+	}
+	return []string{f[0], f[1], f[2], occupation, f[4]}
+}
+
+// convertDelimited lee un archivo sin cabecera separado por sep (inputs.Open transcodifica Latin-1 a UTF-8)
+// y escribe un CSV con la cabecera indicada aplicando transform a cada fila
+func convertDelimited(inPath, outPath, sep string, header []string, transform func([]string) []string) error {
+	in, err := inputs.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(bufio.NewWriter(out))
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		row := transform(strings.Split(line, sep))
+		if row == nil {
+			continue
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// passFields copia las primeras n columnas (descarta filas más cortas)
+func passFields(n int) func([]string) []string {
+	return func(f []string) []string {
+		if len(f) < n {
+			return nil
+		}
+		row := make([]string, n)
+		for i := 0; i < n; i++ {
+			row[i] = strings.TrimSpace(f[i])
+		}
+		return row
+	}
+}
+
+// ml100kMovie convierte una fila de u.item (géneros one-hot) a movieId,title,genres
+func ml100kMovie(f []string) []string {
+	if len(f) < 5 {
+		return nil
+	}
+	var genres []string
+	for i, flag := range f[5:] {
+		if i < len(ml100kGenres) && i > 0 && strings.TrimSpace(flag) == "1" {
+			genres = append(genres, ml100kGenres[i])
+		}
+	}
+	genresStr := ml100kGenres[0]
+	if len(genres) > 0 {
+		genresStr = strings.Join(genres, "|")
+	}
+	return []string{strings.TrimSpace(f[0]), strings.TrimSpace(f[1]), genresStr}
+}
+
+// ml1mMovie convierte una fila de movies.dat normalizando los nombres de género a los de ml-latest
+func ml1mMovie(f []string) []string {
+	if len(f) < 3 {
+		return nil
+	}
+	genres := strings.ReplaceAll(strings.TrimSpace(f[2]), "Children's", "Children")
+	return []string{strings.TrimSpace(f[0]), strings.TrimSpace(f[1]), genres}
+}
@@ -5,50 +5,49 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math"
 	"os"
-	"regexp"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"pc4_etl/internal/inputs"
 	"pc4_etl/internal/mappers"
 	"pc4_etl/internal/models"
 )
 
-// LoadLinks carga los links desde links.csv
-func LoadLinks(path string) (map[int]*models.Links, error) {
-	f, err := os.Open(path)
+// LoadLinks carga los links desde links.csv; omite las filas sin movieId válido (<= 0) y devuelve
+// cuántas filas repetidas sobrescribieron un link anterior (gana la última)
+func LoadLinks(path string) (map[int]*models.Links, int, error) {
+	r, err := inputs.OpenCSV(path, "links", "movieId", "imdbId", "tmdbId")
 	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
-
-	// skip header
-	if _, err := r.Read(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
+	defer r.Close()
+	movieCol, imdbCol, tmdbCol := r.Index("movieId"), r.Index("imdbId"), r.Index("tmdbId")
 
 	links := make(map[int]*models.Links)
+	overwritten := 0
 	for {
 		rec, err := r.Read()
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 3 {
+		if err != nil {
 			continue
 		}
 
-		movieId, _ := strconv.Atoi(rec[0])
-		imdbId := strings.TrimSpace(rec[1])
-		tmdbId := strings.TrimSpace(rec[2])
+		movieId, _ := strconv.Atoi(inputs.Field(rec, movieCol))
+		if movieId <= 0 {
+			continue
+		}
+		imdbId := strings.TrimSpace(inputs.Field(rec, imdbCol))
+		tmdbId := strings.TrimSpace(inputs.Field(rec, tmdbCol))
 
-		link := &models.Links{}
-		if movieId > 0 {
-			link.Movielens = fmt.Sprintf("https://movielens.org/movies/%d", movieId)
+		link := &models.Links{
+			Movielens: fmt.Sprintf("https://movielens.org/movies/%d", movieId),
 		}
 		if imdbId != "" {
 			link.IMDB = fmt.Sprintf("http://www.imdb.com/title/tt%s/", imdbId)
@@ -57,26 +56,22 @@ func LoadLinks(path string) (map[int]*models.Links, error) {
 			link.TMDB = fmt.Sprintf("https://www.themoviedb.org/movie/%s", tmdbId)
 		}
 
+		if _, dup := links[movieId]; dup {
+			overwritten++
+		}
 		links[movieId] = link
 	}
-	return links, nil
+	return links, overwritten, nil
 }
 
 // LoadGenomeTags carga el mapeo de tagId -> tag
 func LoadGenomeTags(path string) (map[int]string, error) {
-	f, err := os.Open(path)
+	r, err := inputs.OpenCSV(path, "genome-tags", "tagId", "tag")
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
-
-	// skip header
-	if _, err := r.Read(); err != nil {
-		return nil, err
-	}
+	defer r.Close()
+	tagIdCol, tagCol := r.Index("tagId"), r.Index("tag")
 
 	tags := make(map[int]string)
 	for {
@@ -84,12 +79,12 @@ func LoadGenomeTags(path string) (map[int]string, error) {
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 2 {
+		if err != nil {
 			continue
 		}
 
-		tagId, _ := strconv.Atoi(rec[0])
-		tag := strings.TrimSpace(rec[1])
+		tagId, _ := strconv.Atoi(inputs.Field(rec, tagIdCol))
+		tag := strings.TrimSpace(inputs.Field(rec, tagCol))
 		tags[tagId] = tag
 	}
 	return tags, nil
@@ -97,19 +92,12 @@ func LoadGenomeTags(path string) (map[int]string, error) {
 
 // LoadGenomeScores carga los scores de relevancia (movieId -> tagId -> relevance)
 func LoadGenomeScores(path string, genomeTagsMap map[int]string, minRelevance float64) (map[int][]models.GenomeTag, error) {
-	f, err := os.Open(path)
+	r, err := inputs.OpenCSV(path, "genome-scores", "movieId", "tagId", "relevance")
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
-
-	// skip header
-	if _, err := r.Read(); err != nil {
-		return nil, err
-	}
+	defer r.Close()
+	movieCol, tagIdCol, relevanceCol := r.Index("movieId"), r.Index("tagId"), r.Index("relevance")
 
 	scores := make(map[int][]models.GenomeTag)
 	for {
@@ -117,13 +105,13 @@ func LoadGenomeScores(path string, genomeTagsMap map[int]string, minRelevance fl
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 3 {
+		if err != nil {
 			continue
 		}
 
-		movieId, _ := strconv.Atoi(rec[0])
-		tagId, _ := strconv.Atoi(rec[1])
-		relevance, _ := strconv.ParseFloat(rec[2], 64)
+		movieId, _ := strconv.Atoi(inputs.Field(rec, movieCol))
+		tagId, _ := strconv.Atoi(inputs.Field(rec, tagIdCol))
+		relevance, _ := strconv.ParseFloat(inputs.Field(rec, relevanceCol), 64)
 
 		// Filtrar solo tags con relevancia mayor al umbral
 		if relevance >= minRelevance {
@@ -146,35 +134,21 @@ func LoadGenomeScores(path string, genomeTagsMap map[int]string, minRelevance fl
 	return scores, nil
 }
 
-// normalizeTag normaliza un tag para eliminar duplicados y typos
-func normalizeTag(tag string) string {
-	// Convertir a minúsculas y trim
-	tag = strings.TrimSpace(strings.ToLower(tag))
-
-	// Eliminar múltiples espacios consecutivos
-	tag = regexp.MustCompile(`\s+`).ReplaceAllString(tag, " ")
-
-	// Eliminar caracteres especiales al inicio/final
-	tag = strings.Trim(tag, ".,;:!?\"'`-_")
-
-	return tag
-}
-
-// LoadUserTags carga los tags de usuarios con frecuencia (movieId -> []tag ordenados por popularidad)
-func LoadUserTags(path string) (map[int][]string, error) {
-	f, err := os.Open(path)
+// LoadUserTags carga los tags de usuarios con frecuencia (movieId -> []tag ordenados por popularidad),
+// aplicando las reglas de limpieza (rules nil = solo normalizar)
+func LoadUserTags(path string, rules *TagRules) (map[int][]string, *TagReport, error) {
+	r, err := inputs.OpenCSV(path, "tags", "userId", "movieId", "tag")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer f.Close()
+	defer r.Close()
+	userCol, movieCol, tagCol := r.Index("userId"), r.Index("movieId"), r.Index("tag")
 
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
-
-	// skip header
-	if _, err := r.Read(); err != nil {
-		return nil, err
+	if rules == nil {
+		rules = &TagRules{}
 	}
+	report := &TagReport{}
+	synonymUses := make(map[string]int)
 
 	// Estructura: movieId -> tag normalizado -> set de userIds que lo asignaron
 	tagFrequency := make(map[int]map[string]map[int]struct{})
@@ -184,23 +158,65 @@ func LoadUserTags(path string) (map[int][]string, error) {
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 4 {
+		if err != nil {
 			continue
 		}
 
-		userId, _ := strconv.Atoi(rec[0])
-		movieId, _ := strconv.Atoi(rec[1])
-		tag := normalizeTag(rec[2])
+		userId, _ := strconv.Atoi(inputs.Field(rec, userCol))
+		movieId, _ := strconv.Atoi(inputs.Field(rec, movieCol))
+		tag := normalizeTag(inputs.Field(rec, tagCol))
+		if tag == "" || movieId <= 0 || userId <= 0 {
+			continue
+		}
+		report.Assignments++
+		if rules.blocked(tag) {
+			report.Blocked++
+			continue
+		}
+		if canonical, ok := rules.Synonyms[tag]; ok && canonical != tag {
+			synonymUses[tag]++
+			tag = canonical
+		}
+
+		if tagFrequency[movieId] == nil {
+			tagFrequency[movieId] = make(map[string]map[int]struct{})
+		}
+		if tagFrequency[movieId][tag] == nil {
+			tagFrequency[movieId][tag] = make(map[int]struct{})
+		}
+		// Agregar el userId al set (para contar usuarios únicos)
+		tagFrequency[movieId][tag][userId] = struct{}{}
+	}
+
+	for tag, n := range synonymUses {
+		report.Merges = append(report.Merges, TagMerge{From: tag, To: rules.Synonyms[tag], Reason: "synonym", Uses: n})
+	}
 
-		if tag != "" && movieId > 0 && userId > 0 {
-			if tagFrequency[movieId] == nil {
-				tagFrequency[movieId] = make(map[string]map[int]struct{})
+	// Unir variantes de escritura y tags cercanos según sus usos globales
+	uses := make(map[string]int)
+	for _, tags := range tagFrequency {
+		for tag, users := range tags {
+			uses[tag] += len(users)
+		}
+	}
+	report.Distinct = len(uses)
+	final, merges := mergeTags(uses, rules.FuzzyDistance)
+	report.Merges = append(report.Merges, merges...)
+	report.Final = report.Distinct - len(final)
+	sort.SliceStable(report.Merges, func(i, j int) bool { return report.Merges[i].From < report.Merges[j].From })
+	for _, tags := range tagFrequency {
+		for from, users := range tags {
+			to, ok := final[from]
+			if !ok {
+				continue
+			}
+			if tags[to] == nil {
+				tags[to] = make(map[int]struct{})
 			}
-			if tagFrequency[movieId][tag] == nil {
-				tagFrequency[movieId][tag] = make(map[int]struct{})
+			for u := range users {
+				tags[to][u] = struct{}{}
 			}
-			// Agregar el userId al set (para contar usuarios únicos)
-			tagFrequency[movieId][tag][userId] = struct{}{}
+			delete(tags, from)
 		}
 	}
 
@@ -239,24 +255,18 @@ func LoadUserTags(path string) (map[int][]string, error) {
 		result[movieId] = finalTags
 	}
 
-	return result, nil
+	return result, report, nil
 }
 
-// LoadRatingStats calcula estadísticas de ratings (movieId -> stats)
-func LoadRatingStats(path string) (map[int]*models.RatingStats, error) {
-	f, err := os.Open(path)
+// LoadRatingStats calcula estadísticas de ratings (movieId -> stats), omitiendo los ratings
+// que el filtro descarta (filter nil = todos)
+func LoadRatingStats(path string, filter models.RatingFilter) (map[int]*models.RatingStats, error) {
+	r, err := inputs.OpenCSV(path, "ratings", "movieId", "rating", "timestamp")
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
-
-	// skip header
-	if _, err := r.Read(); err != nil {
-		return nil, err
-	}
+	defer r.Close()
+	movieCol, ratingCol, tsCol := r.Index("movieId"), r.Index("rating"), r.Index("timestamp")
 
 	// Acumuladores
 	type accumulator struct {
@@ -272,13 +282,28 @@ func LoadRatingStats(path string) (map[int]*models.RatingStats, error) {
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 4 {
+		if err != nil {
 			continue
 		}
 
-		movieId, _ := strconv.Atoi(rec[1])
-		rating, _ := strconv.ParseFloat(rec[2], 64)
-		timestamp, _ := strconv.ParseInt(rec[3], 10, 64)
+		movieId, _ := strconv.Atoi(inputs.Field(rec, movieCol))
+		rating, err := strconv.ParseFloat(strings.TrimSpace(inputs.Field(rec, ratingCol)), 64)
+		timestamp, _ := strconv.ParseInt(inputs.Field(rec, tsCol), 10, 64)
+
+		if filter != nil {
+			// NaN marca un rating no numérico para que el filtro lo cuente
+			if err != nil {
+				rating = math.NaN()
+			}
+			doc := models.RatingDoc{MovieID: movieId, Rating: rating, Timestamp: timestamp}
+			if !filter.Keep(&doc) {
+				continue
+			}
+			movieId, rating, timestamp = doc.MovieID, doc.Rating, doc.Timestamp
+		}
+		if math.IsNaN(rating) || math.IsInf(rating, 0) {
+			continue
+		}
 
 		if accums[movieId] == nil {
 			accums[movieId] = &accumulator{}
@@ -311,21 +336,53 @@ func LoadRatingStats(path string) (map[int]*models.RatingStats, error) {
 	return stats, nil
 }
 
-// LoadItemMap carga el mapeo movieId -> iIdx desde item_map.csv
-func LoadItemMap(path string) (map[int]int, error) {
-	f, err := os.Open(path)
+// LoadRatings carga todos los ratings de ratings.csv en memoria; omite las filas sin userId/movieId
+// válidos y devuelve cuántas se omitieron por un rating no numérico o infinito
+func LoadRatings(path string) ([]models.RatingDoc, int, error) {
+	r, err := inputs.OpenCSV(path, "ratings", "userId", "movieId", "rating")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	defer f.Close()
+	defer r.Close()
+	uCol, mCol, rCol, tCol := r.Index("userId"), r.Index("movieId"), r.Index("rating"), r.Index("timestamp")
 
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
+	var ratings []models.RatingDoc
+	invalid := 0
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+
+		doc := models.RatingDoc{}
+		doc.UserID, _ = strconv.Atoi(inputs.Field(rec, uCol))
+		doc.MovieID, _ = strconv.Atoi(inputs.Field(rec, mCol))
+		if doc.UserID <= 0 || doc.MovieID <= 0 {
+			continue
+		}
+		doc.Rating, err = strconv.ParseFloat(strings.TrimSpace(inputs.Field(rec, rCol)), 64)
+		if err != nil || math.IsNaN(doc.Rating) || math.IsInf(doc.Rating, 0) {
+			invalid++
+			continue
+		}
+		doc.Timestamp, _ = strconv.ParseInt(inputs.Field(rec, tCol), 10, 64)
+		ratings = append(ratings, doc)
+	}
 
-	// skip header
-	if _, err := r.Read(); err != nil {
+	return ratings, invalid, nil
+}
+
+// LoadItemMap carga el mapeo movieId -> iIdx desde item_map.csv
+func LoadItemMap(path string) (map[int]int, error) {
+	r, err := inputs.OpenCSV(path, "item_map", "movieId", "iIdx")
+	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
+	movieCol, idxCol := r.Index("movieId"), r.Index("iIdx")
 
 	itemMap := make(map[int]int)
 	for {
@@ -333,12 +390,12 @@ func LoadItemMap(path string) (map[int]int, error) {
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 2 {
+		if err != nil {
 			continue
 		}
 
-		movieId, _ := strconv.Atoi(rec[0])
-		iIdx, _ := strconv.Atoi(rec[1])
+		movieId, _ := strconv.Atoi(inputs.Field(rec, movieCol))
+		iIdx, _ := strconv.Atoi(inputs.Field(rec, idxCol))
 
 		if movieId > 0 {
 			itemMap[movieId] = iIdx
@@ -348,21 +405,49 @@ func LoadItemMap(path string) (map[int]int, error) {
 	return itemMap, nil
 }
 
-// LoadUserMap carga el mapeo userId -> uIdx desde user_map.csv
-func LoadUserMap(path string) (map[int]int, error) {
-	f, err := os.Open(path)
+// LoadDemographics carga la demografía convertida por ConvertDataset (users.csv: userId, gender,
+// age, occupation, zip)
+func LoadDemographics(path string) (map[int]models.Demographics, error) {
+	r, err := inputs.OpenCSV(path, "users", "userId", "gender", "age", "occupation", "zip")
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	defer r.Close()
+	userCol, genderCol, ageCol := r.Index("userId"), r.Index("gender"), r.Index("age")
+	occupationCol, zipCol := r.Index("occupation"), r.Index("zip")
 
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
+	demographics := make(map[int]models.Demographics)
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		uid, _ := strconv.Atoi(inputs.Field(rec, userCol))
+		if uid <= 0 {
+			continue
+		}
+		age, _ := strconv.Atoi(strings.TrimSpace(inputs.Field(rec, ageCol)))
+		demographics[uid] = models.Demographics{
+			Gender:     strings.TrimSpace(inputs.Field(rec, genderCol)),
+			Age:        age,
+			Occupation: strings.TrimSpace(inputs.Field(rec, occupationCol)),
+			Zip:        strings.TrimSpace(inputs.Field(rec, zipCol)),
+		}
+	}
+	return demographics, nil
+}
 
-	// skip header
-	if _, err := r.Read(); err != nil {
+// LoadUserMap carga el mapeo userId -> uIdx desde user_map.csv
+func LoadUserMap(path string) (map[int]int, error) {
+	r, err := inputs.OpenCSV(path, "user_map", "userId", "uIdx")
+	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
+	userCol, idxCol := r.Index("userId"), r.Index("uIdx")
 
 	userMap := make(map[int]int)
 	for {
@@ -370,12 +455,12 @@ func LoadUserMap(path string) (map[int]int, error) {
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 2 {
+		if err != nil {
 			continue
 		}
 
-		userId, _ := strconv.Atoi(rec[0])
-		uIdx, _ := strconv.Atoi(rec[1])
+		userId, _ := strconv.Atoi(inputs.Field(rec, userCol))
+		uIdx, _ := strconv.Atoi(inputs.Field(rec, idxCol))
 
 		if userId > 0 {
 			userMap[userId] = uIdx
@@ -385,21 +470,112 @@ func LoadUserMap(path string) (map[int]int, error) {
 	return userMap, nil
 }
 
-// LoadSimilarities carga las similitudes desde item_topk_cosine_conc.csv
-func LoadSimilarities(path string, itemMapper *mappers.IDMapper) (map[int][]models.Neighbor, error) {
-	f, err := os.Open(path)
+// LoadKeyMap carga el mapeo clave string -> id desde item_keys.csv / user_keys.csv
+func LoadKeyMap(path, idHeader string) (map[string]int, error) {
+	r, err := inputs.OpenCSV(path, "keys", "key", idHeader)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	defer r.Close()
+	keyCol, idCol := r.Index("key"), r.Index(idHeader)
+
+	keyMap := make(map[string]int)
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+
+		key := inputs.Field(rec, keyCol)
+		id, _ := strconv.Atoi(inputs.Field(rec, idCol))
+		if key != "" && id > 0 {
+			keyMap[key] = id
+		}
+	}
+
+	return keyMap, nil
+}
+
+// AssignItemIndices asigna iIdx a cada movieId de movies.csv en el orden del archivo, igual que
+// ProcessMovies, para que las similitudes de películas sin iIdx previo (item_map vacío o parcial)
+// puedan resolverse antes de procesar las películas. Devuelve cuántos iIdx nuevos se asignaron
+func AssignItemIndices(path string, itemMapper *mappers.IDMapper) (int, error) {
+	r, err := inputs.OpenCSV(path, "movies", "movieId")
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	movieCol := r.Index("movieId")
+
+	before := itemMapper.Count()
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		// Mismo criterio que ProcessMovies (incluidas filas sin movieId válido) para que los
+		// índices resultantes coincidan
+		mid, _ := strconv.Atoi(inputs.Field(rec, movieCol))
+		itemMapper.GetOrCreate(mid)
+	}
+	return itemMapper.Count() - before, nil
+}
+
+// AssignUserIndices asigna uIdx a los userId de ratings.csv en orden ascendente, igual que
+// ProcessUsers, para que la matriz de ratings (que se exporta antes de generar los usuarios) no
+// descarte los usuarios sin uIdx previo. Devuelve cuántos uIdx nuevos se asignaron
+func AssignUserIndices(path string, userMapper *mappers.IDMapper) (int, error) {
+	r, err := inputs.OpenCSV(path, "ratings", "userId")
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	userCol := r.Index("userId")
+
+	users := make(map[int]struct{})
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		if uid, _ := strconv.Atoi(inputs.Field(rec, userCol)); uid > 0 {
+			users[uid] = struct{}{}
+		}
+	}
+	userIds := make([]int, 0, len(users))
+	for uid := range users {
+		userIds = append(userIds, uid)
+	}
+	sort.Ints(userIds)
 
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
+	before := userMapper.Count()
+	for _, uid := range userIds {
+		userMapper.GetOrCreate(uid)
+	}
+	return userMapper.Count() - before, nil
+}
 
-	// skip header
-	if _, err := r.Read(); err != nil {
+// LoadSimilarities carga las similitudes desde item_topk_cosine_conc.csv, con índices en la base
+// del mapeador de items. Con dropOrphans (--orphans drop) descarta las filas cuyo iIdx o
+// neighborIdx no está en item_map; si no, las conserva sin movieId
+func LoadSimilarities(path string, itemMapper *mappers.IDMapper, dropOrphans bool) (map[int][]models.Neighbor, error) {
+	r, err := inputs.OpenCSV(path, "similarities", "iIdx", "neighborIdx", "similarity")
+	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
+	iCol, jCol, simCol := r.Index("iIdx"), r.Index("neighborIdx"), r.Index("similarity")
+
+	base := itemMapper.Base()
 
 	// Crear reverse map: iIdx -> movieId
 	itemMap := itemMapper.GetMapping()
@@ -415,17 +591,23 @@ func LoadSimilarities(path string, itemMapper *mappers.IDMapper) (map[int][]mode
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 3 {
+		if err != nil {
 			continue
 		}
 
-		iIdx, _ := strconv.Atoi(rec[0])
-		jIdx, _ := strconv.Atoi(rec[1])
-		sim, _ := strconv.ParseFloat(rec[2], 64)
+		iIdx, ierr := strconv.Atoi(inputs.Field(rec, iCol))
+		jIdx, jerr := strconv.Atoi(inputs.Field(rec, jCol))
+		sim, _ := strconv.ParseFloat(inputs.Field(rec, simCol), 64)
 
-		if iIdx > 0 && jIdx > 0 {
-			// Obtener movieId del jIdx
-			jMovieId := reverseMap[jIdx]
+		// Índices no numéricos o menores que la base (--index-base) se descartan
+		// (validation.CheckMappings los reporta)
+		if ierr == nil && jerr == nil && iIdx >= base && jIdx >= base {
+			// Sin movieId para el item o el vecino es un huérfano (validation.CheckInputs los
+			// reporta): se aplica la misma política que a los ratings de películas inexistentes
+			jMovieId, ok := reverseMap[jIdx]
+			if _, known := reverseMap[iIdx]; (!ok || !known) && dropOrphans {
+				continue
+			}
 
 			neighbor := models.Neighbor{
 				MovieID: jMovieId,
@@ -439,21 +621,75 @@ func LoadSimilarities(path string, itemMapper *mappers.IDMapper) (map[int][]mode
 	return similarities, nil
 }
 
-// ExtractUniqueGenres extrae todos los géneros únicos del archivo movies.csv
-func ExtractUniqueGenres(path string) ([]string, error) {
-	f, err := os.Open(path)
+// RemapSimilarities reescribe item_topk_cosine_conc.csv con los iIdx de un item_map compactado
+// (ver mappers.Compact). Las filas cuyo iIdx o neighborIdx no tiene un único movieId en oldMap, o
+// cuyo movieId fue descartado, se omiten. Devuelve las filas escritas y las omitidas
+func RemapSimilarities(inPath, outPath string, oldMap, newMap map[int]int) (int, int, error) {
+	// iIdx anterior -> iIdx nuevo (-1 = ambiguo o descartado)
+	remap := make(map[int]int, len(oldMap))
+	for movieId, oldIdx := range oldMap {
+		newIdx, ok := newMap[movieId]
+		if _, seen := remap[oldIdx]; seen || !ok {
+			newIdx = -1
+		}
+		remap[oldIdx] = newIdx
+	}
+
+	r, err := inputs.OpenCSV(inPath, "similarities", "iIdx", "neighborIdx", "similarity")
 	if err != nil {
-		return nil, err
+		return 0, 0, err
+	}
+	defer r.Close()
+	iCol, jCol, simCol := r.Index("iIdx"), r.Index("neighborIdx"), r.Index("similarity")
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return 0, 0, err
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return 0, 0, err
 	}
 	defer f.Close()
+	w := csv.NewWriter(bufio.NewWriter(f))
+	if err := w.Write([]string{"iIdx", "neighborIdx", "similarity"}); err != nil {
+		return 0, 0, err
+	}
 
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
+	written, skipped := 0, 0
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			skipped++
+			continue
+		}
+		iIdx, ierr := strconv.Atoi(inputs.Field(rec, iCol))
+		jIdx, jerr := strconv.Atoi(inputs.Field(rec, jCol))
+		newI, iok := remap[iIdx]
+		newJ, jok := remap[jIdx]
+		if ierr != nil || jerr != nil || !iok || !jok || newI < 0 || newJ < 0 {
+			skipped++
+			continue
+		}
+		if err := w.Write([]string{strconv.Itoa(newI), strconv.Itoa(newJ), inputs.Field(rec, simCol)}); err != nil {
+			return written, skipped, err
+		}
+		written++
+	}
+	w.Flush()
+	return written, skipped, w.Error()
+}
 
-	// skip header
-	if _, err := r.Read(); err != nil {
+// ExtractUniqueGenres extrae todos los géneros únicos del archivo movies.csv
+func ExtractUniqueGenres(path string) ([]string, error) {
+	r, err := inputs.OpenCSV(path, "movies", "genres")
+	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
+	genresCol := r.Index("genres")
 
 	genresMap := make(map[string]struct{})
 	for {
@@ -461,12 +697,12 @@ func ExtractUniqueGenres(path string) ([]string, error) {
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 3 {
+		if err != nil {
 			continue
 		}
 
-		// Los géneros están en la tercera columna, separados por |
-		genresStr := strings.TrimSpace(rec[2])
+		// Los géneros están separados por |
+		genresStr := strings.TrimSpace(inputs.Field(rec, genresCol))
 		if genresStr == "" || genresStr == "(no genres listed)" {
 			continue
 		}
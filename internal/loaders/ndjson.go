@@ -0,0 +1,389 @@
+package loaders
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pc4_etl/internal/inputs"
+	"pc4_etl/internal/models"
+)
+
+// ExportState es el estado reconstruido desde un out/ previo o un mongoexport
+// (movies.ndjson, users.ndjson, ratings.ndjson y similarities.ndjson)
+type ExportState struct {
+	Movies       []models.MovieDoc
+	Users        []models.UserDoc
+	Similarities []models.SimilarityDoc
+	Ratings      int // ratings copiados a ratings.csv (no se mantienen en memoria)
+}
+
+// LoadExport lee los NDJSON de dir y escribe movies.csv y ratings.csv en stagingDir con el layout
+// de ml-latest, de modo que los procesadores puedan regenerar las salidas (re-enriquecer,
+// recalcular estadísticas, exportar derivados). Los archivos ausentes se omiten.
+func LoadExport(dir, stagingDir string) (*ExportState, *DatasetFiles, error) {
+	if err := os.MkdirAll(stagingDir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	state := &ExportState{}
+	files := &DatasetFiles{
+		Format:  FormatNDJSON,
+		Movies:  filepath.Join(stagingDir, "movies.csv"),
+		Ratings: filepath.Join(stagingDir, "ratings.csv"),
+	}
+
+	found := 0
+	optional := func(err error) error {
+		if err == nil {
+			found++
+			return nil
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var err error
+	if state.Movies, err = LoadMoviesNDJSON(filepath.Join(dir, "movies.ndjson")); optional(err) != nil {
+		return nil, nil, err
+	}
+	if state.Users, err = LoadUsersNDJSON(filepath.Join(dir, "users.ndjson")); optional(err) != nil {
+		return nil, nil, err
+	}
+	if state.Similarities, err = LoadSimilaritiesNDJSON(filepath.Join(dir, "similarities.ndjson")); optional(err) != nil {
+		return nil, nil, err
+	}
+	if state.Ratings, err = stageRatingsNDJSON(filepath.Join(dir, "ratings.ndjson"), files.Ratings); optional(err) != nil {
+		return nil, nil, err
+	}
+	if found == 0 {
+		return nil, nil, fmt.Errorf("%s: no se encontró ningún NDJSON exportado (movies, users, ratings, similarities)", dir)
+	}
+
+	if err := stageMovies(state.Movies, files.Movies); err != nil {
+		return nil, nil, err
+	}
+	return state, files, nil
+}
+
+// LoadMoviesNDJSON lee movies.ndjson en documentos de película
+func LoadMoviesNDJSON(path string) ([]models.MovieDoc, error) {
+	var docs []models.MovieDoc
+	err := ReadNDJSON(path, func(line []byte) error {
+		var doc models.MovieDoc
+		if err := DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// LoadUsersNDJSON lee users.ndjson en documentos de usuario
+func LoadUsersNDJSON(path string) ([]models.UserDoc, error) {
+	var docs []models.UserDoc
+	err := ReadNDJSON(path, func(line []byte) error {
+		var doc models.UserDoc
+		if err := DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// LoadRatingsNDJSON lee ratings.ndjson en memoria
+func LoadRatingsNDJSON(path string) ([]models.RatingDoc, error) {
+	var docs []models.RatingDoc
+	err := ReadNDJSON(path, func(line []byte) error {
+		var doc models.RatingDoc
+		if err := DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// LoadSimilaritiesNDJSON lee similarities.ndjson en documentos de similitud
+func LoadSimilaritiesNDJSON(path string) ([]models.SimilarityDoc, error) {
+	var docs []models.SimilarityDoc
+	err := ReadNDJSON(path, func(line []byte) error {
+		var doc models.SimilarityDoc
+		if err := DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// Links devuelve los links de cada película exportada
+func (s *ExportState) Links() map[int]*models.Links {
+	links := make(map[int]*models.Links)
+	for _, doc := range s.Movies {
+		if doc.Links != nil {
+			links[doc.MovieID] = doc.Links
+		}
+	}
+	return links
+}
+
+// GenomeTags devuelve los genome tags de cada película exportada
+func (s *ExportState) GenomeTags() map[int][]models.GenomeTag {
+	genome := make(map[int][]models.GenomeTag)
+	for _, doc := range s.Movies {
+		if len(doc.GenomeTags) > 0 {
+			genome[doc.MovieID] = doc.GenomeTags
+		}
+	}
+	return genome
+}
+
+// UserTags devuelve los user tags de cada película exportada
+func (s *ExportState) UserTags() map[int][]string {
+	tags := make(map[int][]string)
+	for _, doc := range s.Movies {
+		if len(doc.UserTags) > 0 {
+			tags[doc.MovieID] = doc.UserTags
+		}
+	}
+	return tags
+}
+
+// RatingStats devuelve las estadísticas exportadas (cuando no hay ratings.ndjson para recalcularlas)
+func (s *ExportState) RatingStats() map[int]*models.RatingStats {
+	stats := make(map[int]*models.RatingStats)
+	for _, doc := range s.Movies {
+		if doc.RatingStats != nil {
+			stats[doc.MovieID] = doc.RatingStats
+		}
+	}
+	return stats
+}
+
+// ExternalData devuelve los datos de TMDB ya obtenidos en la exportación
+func (s *ExportState) ExternalData() map[int]*models.ExternalData {
+	external := make(map[int]*models.ExternalData)
+	for _, doc := range s.Movies {
+		if doc.ExternalData != nil {
+			external[doc.MovieID] = doc.ExternalData
+		}
+	}
+	return external
+}
+
+// ItemMap reconstruye movieId -> iIdx desde movies y similarities
+func (s *ExportState) ItemMap() map[int]int {
+	itemMap := make(map[int]int)
+	for _, doc := range s.Similarities {
+		if doc.MovieID > 0 {
+			itemMap[doc.MovieID] = doc.IIdx
+		}
+		for _, n := range doc.Neighbors {
+			if n.MovieID > 0 {
+				itemMap[n.MovieID] = n.IIdx
+			}
+		}
+	}
+	for _, doc := range s.Movies {
+		if doc.MovieID > 0 && doc.IIdx != nil {
+			itemMap[doc.MovieID] = *doc.IIdx
+		}
+	}
+	return itemMap
+}
+
+// UserMap reconstruye userId -> uIdx desde users
+func (s *ExportState) UserMap() map[int]int {
+	userMap := make(map[int]int)
+	for _, doc := range s.Users {
+		if doc.UserID > 0 && doc.UIdx != nil {
+			userMap[doc.UserID] = *doc.UIdx
+		}
+	}
+	return userMap
+}
+
+// UserDocs devuelve los usuarios exportados por userId (para conservar nombres y passwords)
+func (s *ExportState) UserDocs() map[int]*models.UserDoc {
+	users := make(map[int]*models.UserDoc, len(s.Users))
+	for i := range s.Users {
+		users[s.Users[i].UserID] = &s.Users[i]
+	}
+	return users
+}
+
+// SimilarityMap devuelve los vecinos por iIdx, con la misma forma que LoadSimilarities
+func (s *ExportState) SimilarityMap() map[int][]models.Neighbor {
+	similarities := make(map[int][]models.Neighbor)
+	for _, doc := range s.Similarities {
+		similarities[doc.IIdx] = append(similarities[doc.IIdx], doc.Neighbors...)
+	}
+	return similarities
+}
+
+// stageMovies escribe movies.csv (movieId,title,genres) con los títulos alternativos y el año
+// (o rango) dentro del título, de modo que se vuelvan a interpretar igual
+func stageMovies(movies []models.MovieDoc, outPath string) error {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(bufio.NewWriter(f))
+	if err := w.Write([]string{"movieId", "title", "genres"}); err != nil {
+		return err
+	}
+	for _, doc := range movies {
+		title := doc.Title
+		// Volver a posponer el artículo ("The Matrix" con sortTitle "matrix" -> "Matrix, The") para
+		// que sortTitle se recalcule igual
+		if n := len(doc.SortTitle); n > 0 && len(title) > n && strings.ToLower(title[len(title)-n:]) == doc.SortTitle {
+			if article := strings.TrimSpace(title[:len(title)-n]); article != "" {
+				title = title[len(title)-n:] + ", " + article
+			}
+		}
+		for _, alt := range doc.AlternateTitles {
+			title = fmt.Sprintf("%s (%s)", title, alt)
+		}
+		switch {
+		case doc.Year != nil && doc.EndYear != nil:
+			title = fmt.Sprintf("%s (%d-%d)", title, *doc.Year, *doc.EndYear)
+		case doc.Year != nil && doc.Ongoing:
+			title = fmt.Sprintf("%s (%d-)", title, *doc.Year)
+		case doc.Year != nil:
+			title = fmt.Sprintf("%s (%d)", title, *doc.Year)
+		}
+		genres := "(no genres listed)"
+		if len(doc.Genres) > 0 {
+			genres = strings.Join(doc.Genres, "|")
+		}
+		if err := w.Write([]string{strconv.Itoa(doc.MovieID), title, genres}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// stageRatingsNDJSON copia ratings.ndjson a ratings.csv sin mantenerlos en memoria
+func stageRatingsNDJSON(inPath, outPath string) (int, error) {
+	out, err := os.Create(outPath)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(bufio.NewWriter(out))
+	if err := w.Write([]string{"userId", "movieId", "rating", "timestamp"}); err != nil {
+		return 0, err
+	}
+	count := 0
+	err = ReadNDJSON(inPath, func(line []byte) error {
+		var doc models.RatingDoc
+		if err := DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		count++
+		return w.Write([]string{
+			strconv.Itoa(doc.UserID),
+			strconv.Itoa(doc.MovieID),
+			strconv.FormatFloat(doc.Rating, 'f', -1, 64),
+			strconv.FormatInt(doc.Timestamp, 10),
+		})
+	})
+	// La cabecera se escribe siempre: sin ratings.ndjson, ratings.csv queda vacío pero legible
+	w.Flush()
+	if err != nil {
+		return count, err
+	}
+	return count, w.Error()
+}
+
+// ReadNDJSON llama a fn con cada línea no vacía del archivo (ver inputs.Open)
+func ReadNDJSON(path string, fn func(line []byte) error) error {
+	rc, err := inputs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// DecodeDoc decodifica un documento NDJSON; si falla, reintenta convirtiendo el Extended JSON
+// de mongoexport ({"$numberLong": "1"}, {"$oid": "..."}, {"$date": ...}) a valores planos
+func DecodeDoc(line []byte, v interface{}) error {
+	err := json.Unmarshal(line, v)
+	if err == nil {
+		return nil
+	}
+	var raw interface{}
+	if json.Unmarshal(line, &raw) != nil {
+		return err
+	}
+	plain, merr := json.Marshal(unwrapExtendedJSON(raw))
+	if merr != nil {
+		return err
+	}
+	return json.Unmarshal(plain, v)
+}
+
+// unwrapExtendedJSON reemplaza los wrappers de tipo de MongoDB por su valor
+func unwrapExtendedJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(x) == 1 {
+			for key, inner := range x {
+				switch key {
+				case "$numberLong", "$numberInt", "$numberDouble", "$numberDecimal":
+					if s, ok := inner.(string); ok {
+						return json.Number(s)
+					}
+					return inner
+				case "$oid", "$symbol":
+					return inner
+				case "$date":
+					return unwrapExtendedJSON(inner)
+				}
+			}
+		}
+		for key, inner := range x {
+			x[key] = unwrapExtendedJSON(inner)
+		}
+		return x
+	case []interface{}:
+		for i, inner := range x {
+			x[i] = unwrapExtendedJSON(inner)
+		}
+		return x
+	default:
+		return v
+	}
+}
@@ -0,0 +1,67 @@
+package loaders
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pc4_etl/internal/models"
+)
+
+// Una exportación sin ratings.ndjson debe dejar un ratings.csv con cabecera (antes quedaba en
+// 0 bytes y ProcessRatings fallaba con EOF)
+func TestLoadExportWithoutRatings(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("movies.ndjson", `{"movieId":1,"title":"Toy Story","year":1995,"genres":["Animation"]}`+"\n")
+	write("users.ndjson", `{"userId":1,"uIdx":0,"name":"Ana"}`+"\n")
+
+	state, files, err := LoadExport(dir, filepath.Join(dir, "staging"))
+	if err != nil {
+		t.Fatalf("LoadExport: %v", err)
+	}
+	if state.Ratings != 0 {
+		t.Errorf("ratings copiados = %d, want 0", state.Ratings)
+	}
+
+	b, err := os.ReadFile(files.Ratings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "userId,movieId,rating,timestamp\n"; got != want {
+		t.Errorf("ratings.csv = %q, want %q", got, want)
+	}
+	stats, err := LoadRatingStats(files.Ratings, nil)
+	if err != nil {
+		t.Fatalf("LoadRatingStats: %v", err)
+	}
+	if len(stats) != 0 {
+		t.Errorf("stats = %d películas, want 0", len(stats))
+	}
+}
+
+// stageMovies vuelve a posponer el artículo para que el título se interprete igual al reimportar
+func TestStageMoviesPostponesArticle(t *testing.T) {
+	year := 1999
+	path := filepath.Join(t.TempDir(), "movies.csv")
+	err := stageMovies([]models.MovieDoc{
+		{MovieID: 1, Title: "The Matrix", SortTitle: "matrix", Year: &year},
+		{MovieID: 2, Title: "Die Hard", SortTitle: "die hard"},
+		{MovieID: 3, Title: "L'Avventura", SortTitle: "avventura"},
+	}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "movieId,title,genres\n1,\"Matrix, The (1999)\",(no genres listed)\n2,Die Hard,(no genres listed)\n3,\"Avventura, L'\",(no genres listed)\n"
+	if string(b) != want {
+		t.Errorf("movies.csv =\n%s\nwant\n%s", b, want)
+	}
+}
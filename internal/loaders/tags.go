@@ -0,0 +1,322 @@
+package loaders
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"pc4_etl/internal/inputs"
+)
+
+var whitespaceRe = regexp.MustCompile(`\s+`)
+
+// minFuzzyLength es el largo mínimo de un tag para agruparlo por distancia de edición: en palabras
+// cortas una letra distinta suele ser otra palabra (boxing/boring, poison/prison, polite/police)
+const minFuzzyLength = 8
+
+// defaultTagSynonyms son sinónimos frecuentes en los tags de MovieLens (tag normalizado -> canónico)
+var defaultTagSynonyms = map[string]string{
+	"scifi":            "sci-fi",
+	"sci fi":           "sci-fi",
+	"science fiction":  "sci-fi",
+	"super hero":       "superhero",
+	"superheroes":      "superhero",
+	"based on a book":  "based on a book",
+	"based on book":    "based on a book",
+	"based on novel":   "based on a book",
+	"based on a novel": "based on a book",
+}
+
+// normalizeTag normaliza un tag para eliminar duplicados y typos
+func normalizeTag(tag string) string {
+	// Convertir a minúsculas y trim
+	tag = strings.TrimSpace(strings.ToLower(tag))
+
+	// Eliminar múltiples espacios consecutivos
+	tag = whitespaceRe.ReplaceAllString(tag, " ")
+
+	// Eliminar caracteres especiales al inicio/final
+	tag = strings.Trim(tag, ".,;:!?\"'`-_")
+
+	return tag
+}
+
+// TagRules son las reglas de limpieza de user tags: sinónimos, lista de bloqueo y
+// agrupamiento por distancia de edición (FuzzyDistance 0 = desactivado)
+type TagRules struct {
+	Synonyms      map[string]string
+	Blocklist     []string
+	FuzzyDistance int
+}
+
+// DefaultTagRules devuelve los sinónimos incluidos, sin agrupamiento por distancia de edición
+// (opcional con --tag-fuzzy-distance)
+func DefaultTagRules() *TagRules {
+	synonyms := make(map[string]string, len(defaultTagSynonyms))
+	for k, v := range defaultTagSynonyms {
+		synonyms[k] = v
+	}
+	return &TagRules{Synonyms: synonyms}
+}
+
+// LoadTagSynonyms agrega los sinónimos de un CSV con columnas tag,canonical
+func (r *TagRules) LoadTagSynonyms(path string) error {
+	f, err := inputs.OpenCSV(path, "tag-synonyms", "tag", "canonical")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tagCol, canonicalCol := f.Index("tag"), f.Index("canonical")
+	for {
+		rec, err := f.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		tag, canonical := normalizeTag(inputs.Field(rec, tagCol)), normalizeTag(inputs.Field(rec, canonicalCol))
+		if tag != "" && canonical != "" {
+			r.Synonyms[tag] = canonical
+		}
+	}
+}
+
+// LoadTagBlocklist agrega los términos bloqueados de un archivo de texto (uno por línea, # comenta).
+// Un tag se descarta si coincide con un término o lo contiene como palabra completa.
+func (r *TagRules) LoadTagBlocklist(path string) error {
+	rc, err := inputs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if term := normalizeTag(line); term != "" {
+			r.Blocklist = append(r.Blocklist, term)
+		}
+	}
+	return scanner.Err()
+}
+
+// blocked indica si el tag contiene un término bloqueado como palabra completa
+func (r *TagRules) blocked(tag string) bool {
+	padded := " " + tag + " "
+	for _, term := range r.Blocklist {
+		if strings.Contains(padded, " "+term+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// TagMerge es una unión aplicada: From pasa a contarse como To
+type TagMerge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"` // "synonym", "spelling" (mismas letras) o "fuzzy" (distancia de edición)
+	Uses   int    `json:"uses"`   // asignaciones (usuario, película) afectadas
+}
+
+// TagReport resume la limpieza de user tags
+type TagReport struct {
+	Assignments int        `json:"assignments"`
+	Distinct    int        `json:"distinct"` // tags distintos después de normalizar
+	Final       int        `json:"final"`    // tags distintos después de unir
+	Blocked     int        `json:"blocked"`  // asignaciones descartadas por la lista de bloqueo
+	Merges      []TagMerge `json:"merges"`
+}
+
+// Lines devuelve el resumen legible (consola y report.txt)
+func (r *TagReport) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("  • Asignaciones: %d, tags distintos: %d -> %d después de unir", r.Assignments, r.Distinct, r.Final),
+	}
+	counts := make(map[string]int)
+	for _, m := range r.Merges {
+		counts[m.Reason]++
+	}
+	if len(r.Merges) > 0 {
+		lines = append(lines, fmt.Sprintf("  • Uniones: %d por sinónimo, %d por escritura, %d por distancia de edición",
+			counts["synonym"], counts["spelling"], counts["fuzzy"]))
+		top := make([]TagMerge, len(r.Merges))
+		copy(top, r.Merges)
+		sort.SliceStable(top, func(i, j int) bool { return top[i].Uses > top[j].Uses })
+		if len(top) > 10 {
+			top = top[:10]
+		}
+		for _, m := range top {
+			lines = append(lines, fmt.Sprintf("    %s -> %s (%s, %d usos)", m.From, m.To, m.Reason, m.Uses))
+		}
+	}
+	if r.Blocked > 0 {
+		lines = append(lines, fmt.Sprintf("  ⚠ Asignaciones bloqueadas: %d", r.Blocked))
+	}
+	return lines
+}
+
+// WriteJSON guarda el reporte completo (incluyendo todas las uniones)
+func (r *TagReport) WriteJSON(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// mergeTags calcula el tag final de cada tag según sus usos globales: primero las variantes con las
+// mismas letras ("sci-fi", "scifi", "sci fi"), luego los tags a distancia de edición <= distance
+// (con los mismos dígitos, para no unir "alien 2" y "alien 3"). Gana el más usado.
+func mergeTags(uses map[string]int, distance int) (map[string]string, []TagMerge) {
+	tags := make([]string, 0, len(uses))
+	for tag := range uses {
+		tags = append(tags, tag)
+	}
+	// El más usado primero (desempate alfabético) para que sea la raíz de su grupo
+	sort.Slice(tags, func(i, j int) bool {
+		if uses[tags[i]] != uses[tags[j]] {
+			return uses[tags[i]] > uses[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	parent := make(map[string]string, len(tags))
+	reason := make(map[string]string)
+	var find func(string) string
+	find = func(t string) string {
+		p, ok := parent[t]
+		if !ok || p == t {
+			return t
+		}
+		root := find(p)
+		parent[t] = root
+		return root
+	}
+
+	// Variantes de escritura
+	bySpelling := make(map[string]string)
+	for _, tag := range tags {
+		k := lettersOnly(tag)
+		if root, ok := bySpelling[k]; ok && k != "" {
+			parent[tag] = root
+			reason[tag] = "spelling"
+			continue
+		}
+		bySpelling[k] = tag
+	}
+
+	// Distancia de edición con vecindario de borrados: dos tags a distancia <= d comparten
+	// alguna variante con hasta d caracteres borrados
+	if distance > 0 {
+		neighborhood := make(map[string][]string)
+		for _, tag := range tags {
+			if find(tag) != tag || len([]rune(tag)) < minFuzzyLength {
+				continue
+			}
+			matched := false
+			for _, v := range deletions(tag, distance) {
+				for _, other := range neighborhood[v] {
+					if !matched && digitsOf(other) == digitsOf(tag) && levenshtein(other, tag) <= distance {
+						parent[tag] = other
+						reason[tag] = "fuzzy"
+						matched = true
+					}
+				}
+			}
+			if !matched {
+				for _, v := range deletions(tag, distance) {
+					neighborhood[v] = append(neighborhood[v], tag)
+				}
+			}
+		}
+	}
+
+	final := make(map[string]string)
+	var merges []TagMerge
+	for _, tag := range tags {
+		root := find(tag)
+		if root != tag {
+			final[tag] = root
+			merges = append(merges, TagMerge{From: tag, To: root, Reason: reason[tag], Uses: uses[tag]})
+		}
+	}
+	return final, merges
+}
+
+// deletions devuelve el tag y todas sus variantes con hasta d caracteres borrados
+func deletions(tag string, d int) []string {
+	seen := map[string]bool{tag: true}
+	level := []string{tag}
+	for i := 0; i < d; i++ {
+		var next []string
+		for _, s := range level {
+			runes := []rune(s)
+			for j := range runes {
+				v := string(runes[:j]) + string(runes[j+1:])
+				if !seen[v] {
+					seen[v] = true
+					next = append(next, v)
+				}
+			}
+		}
+		level = next
+	}
+	out := make([]string, 0, len(seen))
+	for v := range seen {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// levenshtein calcula la distancia de edición entre dos cadenas
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// lettersOnly deja solo letras y dígitos ("sci-fi" -> "scifi")
+func lettersOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// digitsOf deja solo los dígitos ("alien 2" -> "2")
+func digitsOf(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,48 @@
+package loaders
+
+import "testing"
+
+// Palabras distintas a una letra de distancia no deben unirse, ni por defecto ni con distancia 1
+func TestMergeTagsKeepsDistinctWords(t *testing.T) {
+	pairs := [][2]string{
+		{"boxing", "boring"},
+		{"poison", "prison"},
+		{"polite", "police"},
+		{"depth", "death"},
+		{"funky", "funny"},
+	}
+	for _, distance := range []int{DefaultTagRules().FuzzyDistance, 1} {
+		for _, p := range pairs {
+			final, merges := mergeTags(map[string]int{p[0]: 3, p[1]: 5}, distance)
+			if len(final) != 0 || len(merges) != 0 {
+				t.Errorf("distancia %d: %s/%s unidos: %v", distance, p[0], p[1], merges)
+			}
+		}
+	}
+}
+
+func TestMergeTagsTypos(t *testing.T) {
+	tests := []struct {
+		from, to string
+		distance int
+		want     string // "" = sin unir
+	}{
+		{"distopia", "dystopia", 1, "dystopia"},
+		{"psycological", "psychological", 1, "psychological"},
+		{"distopia", "dystopia", 0, ""},
+		{"sci fi", "sci-fi", 0, "sci-fi"}, // variante de escritura: siempre
+		{"alien 2", "alien 3", 1, ""},
+	}
+	for _, tt := range tests {
+		final, _ := mergeTags(map[string]int{tt.from: 1, tt.to: 10}, tt.distance)
+		if got := final[tt.from]; got != tt.want {
+			t.Errorf("mergeTags(%q, %q, %d) = %q, want %q", tt.from, tt.to, tt.distance, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultTagRulesFuzzyOff(t *testing.T) {
+	if d := DefaultTagRules().FuzzyDistance; d != 0 {
+		t.Errorf("FuzzyDistance por defecto = %d, want 0", d)
+	}
+}
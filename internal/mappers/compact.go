@@ -0,0 +1,85 @@
+package mappers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Remap registra el cambio de índice de un id al compactar un mapeo (NewIdx -1 = descartado)
+type Remap struct {
+	ID     int
+	OldIdx int
+	NewIdx int
+}
+
+// Compact reasigna índices contiguos desde base conservando el orden original: primero los
+// índices válidos (por índice y, si lo comparten varios ids, por id), luego los menores que base
+// (por id). Los ids para los que keep devuelve false se descartan. Devuelve el mapeo nuevo y la
+// tabla de cambios de los ids cuyo índice cambió
+func Compact(mapping map[int]int, base int, keep func(id int) bool) (map[int]int, []Remap) {
+	entries := make([]Remap, 0, len(mapping))
+	for id, idx := range mapping {
+		entries = append(entries, Remap{ID: id, OldIdx: idx, NewIdx: -1})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (a.OldIdx < base) != (b.OldIdx < base) {
+			return b.OldIdx < base
+		}
+		if a.OldIdx != b.OldIdx && a.OldIdx >= base {
+			return a.OldIdx < b.OldIdx
+		}
+		return a.ID < b.ID
+	})
+
+	compacted := make(map[int]int, len(entries))
+	var remap []Remap
+	next := base
+	for _, e := range entries {
+		if keep == nil || keep(e.ID) {
+			e.NewIdx = next
+			compacted[e.ID] = next
+			next++
+		}
+		if e.NewIdx != e.OldIdx {
+			remap = append(remap, e)
+		}
+	}
+	return compacted, remap
+}
+
+// SaveRemap guarda la tabla de cambios de índice (header: <idHeader>,old_<idxHeader>,new_<idxHeader>)
+// para que los modelos entrenados con los índices anteriores puedan reordenar sus embeddings
+func SaveRemap(path, idHeader, idxHeader string, remap []Remap) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(bufio.NewWriter(f))
+	defer w.Flush()
+
+	if err := w.Write([]string{idHeader, "old_" + idxHeader, "new_" + idxHeader}); err != nil {
+		return err
+	}
+	for _, r := range remap {
+		if err := w.Write([]string{
+			strconv.Itoa(r.ID),
+			strconv.Itoa(r.OldIdx),
+			strconv.Itoa(r.NewIdx),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
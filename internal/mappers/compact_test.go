@@ -0,0 +1,32 @@
+package mappers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	// 30 y 40 comparten el índice 5; 50 quedó bajo la base; 20 se descarta
+	mapping := map[int]int{10: 1, 20: 3, 40: 5, 30: 5, 50: 0}
+	keep := func(id int) bool { return id != 20 }
+
+	got, remap := Compact(mapping, 1, keep)
+	want := map[int]int{10: 1, 30: 2, 40: 3, 50: 4}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compact() = %v, want %v", got, want)
+	}
+	wantRemap := []Remap{
+		{ID: 20, OldIdx: 3, NewIdx: -1},
+		{ID: 30, OldIdx: 5, NewIdx: 2},
+		{ID: 40, OldIdx: 5, NewIdx: 3},
+		{ID: 50, OldIdx: 0, NewIdx: 4},
+	}
+	if !reflect.DeepEqual(remap, wantRemap) {
+		t.Errorf("remap = %v, want %v", remap, wantRemap)
+	}
+
+	// Un mapeo ya contiguo no cambia
+	if _, remap := Compact(map[int]int{7: 0, 8: 1}, 0, nil); len(remap) != 0 {
+		t.Errorf("remap de un mapeo contiguo = %v, want vacío", remap)
+	}
+}
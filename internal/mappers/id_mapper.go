@@ -10,17 +10,24 @@ import (
 	"sync"
 )
 
+// ValidIndexBase indica si la base de índices es soportada (0: iIdx/uIdx desde 0, 1: desde 1)
+func ValidIndexBase(base int) bool {
+	return base == 0 || base == 1
+}
+
 // IDMapper gestiona el mapeo thread-safe de IDs a índices secuenciales
 type IDMapper struct {
 	mu      sync.RWMutex
 	mapping map[int]int
+	base    int
 	nextIdx int
 	changed bool
 }
 
-// NewIDMapper crea un nuevo mapeador con el mapa inicial cargado
-func NewIDMapper(initialMap map[int]int) *IDMapper {
-	maxIdx := -1
+// NewIDMapper crea un nuevo mapeador con el mapa inicial cargado; los índices nuevos continúan
+// después del mayor existente y nunca son menores que base (0 o 1)
+func NewIDMapper(initialMap map[int]int, base int) *IDMapper {
+	maxIdx := base - 1
 	for _, idx := range initialMap {
 		if idx > maxIdx {
 			maxIdx = idx
@@ -28,11 +35,17 @@ func NewIDMapper(initialMap map[int]int) *IDMapper {
 	}
 	return &IDMapper{
 		mapping: initialMap,
+		base:    base,
 		nextIdx: maxIdx + 1,
 		changed: false,
 	}
 }
 
+// Base devuelve el primer índice válido (0 o 1)
+func (m *IDMapper) Base() int {
+	return m.base
+}
+
 // Get obtiene el índice mapeado, o -1 si no existe
 func (m *IDMapper) Get(id int) int {
 	m.mu.RLock()
@@ -186,3 +199,113 @@ func SaveUserMap(path string, userMap map[int]int) error {
 
 	return nil
 }
+
+// KeyMapper asigna IDs enteros secuenciales (desde 1) a claves string de datasets externos
+// (ej: ASIN de Amazon, ISBN de Book-Crossing) para que puedan usarse como movieId/userId
+type KeyMapper struct {
+	mu      sync.RWMutex
+	mapping map[string]int
+	nextID  int
+	changed bool
+}
+
+// NewKeyMapper crea un mapeador de claves con el mapa inicial cargado
+func NewKeyMapper(initialMap map[string]int) *KeyMapper {
+	maxID := 0
+	for _, id := range initialMap {
+		if id > maxID {
+			maxID = id
+		}
+	}
+	return &KeyMapper{
+		mapping: initialMap,
+		nextID:  maxID + 1,
+	}
+}
+
+// GetOrCreate obtiene el ID de la clave o asigna el siguiente disponible
+func (m *KeyMapper) GetOrCreate(key string) int {
+	m.mu.RLock()
+	if id, ok := m.mapping[key]; ok {
+		m.mu.RUnlock()
+		return id
+	}
+	m.mu.RUnlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id, ok := m.mapping[key]; ok {
+		return id
+	}
+	id := m.nextID
+	m.mapping[key] = id
+	m.nextID++
+	m.changed = true
+	return id
+}
+
+// HasChanged indica si se asignaron IDs nuevos
+func (m *KeyMapper) HasChanged() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.changed
+}
+
+// GetMapping devuelve una copia del mapa actual
+func (m *KeyMapper) GetMapping() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	copy := make(map[string]int, len(m.mapping))
+	for k, v := range m.mapping {
+		copy[k] = v
+	}
+	return copy
+}
+
+// Count devuelve el número de claves mapeadas
+func (m *KeyMapper) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.mapping)
+}
+
+// SaveKeyMap guarda el mapeo clave -> id a un archivo CSV (header: key,<idHeader>)
+func SaveKeyMap(path, idHeader string, keyMap map[string]int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(bufio.NewWriter(f))
+	defer w.Flush()
+
+	if err := w.Write([]string{"key", idHeader}); err != nil {
+		return err
+	}
+
+	// Ordenar por id para mantener consistencia
+	type kv struct {
+		key string
+		id  int
+	}
+	entries := make([]kv, 0, len(keyMap))
+	for key, id := range keyMap {
+		entries = append(entries, kv{key, id})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].id < entries[j].id
+	})
+
+	for _, e := range entries {
+		if err := w.Write([]string{e.key, strconv.Itoa(e.id)}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import "encoding/json"
+
+// Genre es un género normalizado según la taxonomía (ver --genres-format objects)
+type Genre struct {
+	ID     string            `json:"id"`
+	Parent string            `json:"parent,omitempty"`
+	Names  map[string]string `json:"names,omitempty"` // idioma -> nombre para mostrar
+}
+
+// MarshalJSON escribe genres como objetos cuando la película tiene GenreObjects
+func (m MovieDoc) MarshalJSON() ([]byte, error) {
+	type plain MovieDoc
+	if m.GenreObjects == nil {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Genres []Genre `json:"genres"`
+	}{plain(m), m.GenreObjects})
+}
+
+// UnmarshalJSON acepta genres como lista de nombres o de objetos (en ese caso Genres son los ids)
+func (m *MovieDoc) UnmarshalJSON(b []byte) error {
+	type plain MovieDoc
+	aux := struct {
+		*plain
+		Genres json.RawMessage `json:"genres"`
+	}{plain: (*plain)(m)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if len(aux.Genres) == 0 || string(aux.Genres) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Genres, &m.Genres); err == nil {
+		return nil
+	}
+	var objects []Genre
+	if err := json.Unmarshal(aux.Genres, &objects); err != nil {
+		return err
+	}
+	m.GenreObjects = objects
+	m.Genres = make([]string, len(objects))
+	for i, g := range objects {
+		m.Genres[i] = g.ID
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package models
+
+// JSONLDPerson representa un schema.org Person (director o actor)
+type JSONLDPerson struct {
+	Type  string `json:"@type"`
+	Name  string `json:"name"`
+	Image string `json:"image,omitempty"`
+}
+
+// JSONLDAggregateRating representa un schema.org AggregateRating
+type JSONLDAggregateRating struct {
+	Type        string  `json:"@type"`
+	RatingValue float64 `json:"ratingValue"`
+	RatingCount int     `json:"ratingCount"`
+	BestRating  float64 `json:"bestRating"`
+	WorstRating float64 `json:"worstRating"`
+}
+
+// JSONLDMovie representa una película como schema.org Movie (datos estructurados para SEO)
+type JSONLDMovie struct {
+	Context         string                 `json:"@context"`
+	Type            string                 `json:"@type"`
+	Identifier      string                 `json:"identifier"`
+	Name            string                 `json:"name"`
+	AlternateName   []string               `json:"alternateName,omitempty"`
+	DatePublished   string                 `json:"datePublished,omitempty"`
+	Genre           []string               `json:"genre,omitempty"`
+	Description     string                 `json:"description,omitempty"`
+	Duration        string                 `json:"duration,omitempty"`
+	Image           string                 `json:"image,omitempty"`
+	Director        *JSONLDPerson          `json:"director,omitempty"`
+	Actor           []JSONLDPerson         `json:"actor,omitempty"`
+	AggregateRating *JSONLDAggregateRating `json:"aggregateRating,omitempty"`
+	SameAs          []string               `json:"sameAs,omitempty"`
+}
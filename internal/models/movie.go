@@ -31,20 +31,35 @@ type ExternalData struct {
 	TMDBFetched bool         `json:"tmdbFetched"`
 }
 
+// SimilarMovie representa un vecino desnormalizado dentro del documento de la película
+type SimilarMovie struct {
+	MovieID   int     `json:"movieId"`
+	Title     string  `json:"title"`
+	Year      *int    `json:"year,omitempty"`
+	PosterURL string  `json:"posterUrl,omitempty"`
+	Sim       float64 `json:"sim"`
+}
+
 // MovieDoc representa el documento completo de una película en MongoDB
 type MovieDoc struct {
-	MovieID      int           `json:"movieId"`
-	IIdx         *int          `json:"iIdx,omitempty"`
-	Title        string        `json:"title"`
-	Year         *int          `json:"year,omitempty"`
-	Genres       []string      `json:"genres"`
-	Links        *Links        `json:"links,omitempty"`
-	GenomeTags   []GenomeTag   `json:"genomeTags,omitempty"`
-	UserTags     []string      `json:"userTags,omitempty"`
-	RatingStats  *RatingStats  `json:"ratingStats,omitempty"`
-	ExternalData *ExternalData `json:"externalData,omitempty"`
-	CreatedAt    string        `json:"createdAt"`
-	UpdatedAt    string        `json:"updatedAt"`
+	MovieID         int            `json:"movieId"`
+	IIdx            *int           `json:"iIdx,omitempty"`
+	Title           string         `json:"title"`
+	SortTitle       string         `json:"sortTitle,omitempty"`       // título sin artículo inicial, en minúsculas
+	AlternateTitles []string       `json:"alternateTitles,omitempty"` // títulos originales y a.k.a.
+	Year            *int           `json:"year,omitempty"`
+	EndYear         *int           `json:"endYear,omitempty"` // fin de un rango de años, ej: "(2007-2009)"
+	Ongoing         bool           `json:"ongoing,omitempty"` // rango abierto, ej: "(2007-)"
+	Genres          []string       `json:"genres"`
+	GenreObjects    []Genre        `json:"-"` // si no es nil se escribe en lugar de Genres
+	Links           *Links         `json:"links,omitempty"`
+	GenomeTags      []GenomeTag    `json:"genomeTags,omitempty"`
+	UserTags        []string       `json:"userTags,omitempty"`
+	RatingStats     *RatingStats   `json:"ratingStats,omitempty"`
+	ExternalData    *ExternalData  `json:"externalData,omitempty"`
+	Similar         []SimilarMovie `json:"similar,omitempty"`
+	CreatedAt       string         `json:"createdAt"`
+	UpdatedAt       string         `json:"updatedAt"`
 }
 
 // TMDBMovieResponse representa la respuesta de la API de TMDB para detalles de película
@@ -14,3 +14,39 @@ type RatingDoc struct {
 	Rating    float64 `json:"rating"`
 	Timestamp int64   `json:"timestamp"`
 }
+
+// RatingFilter decide si un rating se conserva; puede corregir el documento (ej: reescalar).
+// Se aplica igual en LoadRatingStats y ProcessRatings para que estadísticas y salida coincidan.
+type RatingFilter interface {
+	Keep(doc *RatingDoc) bool
+}
+
+// BucketRating representa un rating dentro de un bucket de usuario
+type BucketRating struct {
+	MovieID   int     `json:"movieId"`
+	IIdx      *int    `json:"iIdx,omitempty"`
+	Rating    float64 `json:"rating"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// UserRatingBucket agrupa hasta N ratings de un usuario (bucket pattern)
+type UserRatingBucket struct {
+	ID           string         `json:"_id"`
+	UserID       int            `json:"userId"`
+	UIdx         *int           `json:"uIdx,omitempty"`
+	Bucket       int            `json:"bucket"`
+	Count        int            `json:"count"`
+	MinTimestamp int64          `json:"minTimestamp"`
+	MaxTimestamp int64          `json:"maxTimestamp"`
+	Ratings      []BucketRating `json:"ratings"`
+}
+
+// SplitRating representa un rating dentro de un split train/validation/test
+type SplitRating struct {
+	UserID    int     `json:"userId"`
+	UIdx      int     `json:"uIdx"`
+	MovieID   int     `json:"movieId"`
+	IIdx      int     `json:"iIdx"`
+	Rating    float64 `json:"rating"`
+	Timestamp int64   `json:"timestamp"`
+}
@@ -2,7 +2,7 @@ package models
 
 // Neighbor representa una película vecina con su similitud
 type Neighbor struct {
-	MovieID int     `json:"movieId"`
+	MovieID int     `json:"movieId,omitempty"` // 0 (omitido) si neighborIdx no está en item_map (--orphans keep)
 	IIdx    int     `json:"iIdx"`
 	Sim     float64 `json:"sim"`
 }
@@ -10,7 +10,7 @@ type Neighbor struct {
 // SimilarityDoc representa el documento de similitudes en MongoDB
 type SimilarityDoc struct {
 	ID        string     `json:"_id"`
-	MovieID   int        `json:"movieId"`
+	MovieID   int        `json:"movieId,omitempty"` // 0 (omitido) si iIdx no está en item_map (--orphans keep)
 	IIdx      int        `json:"iIdx"`
 	Metric    string     `json:"metric"`
 	K         int        `json:"k"`
@@ -18,6 +18,18 @@ type UserDoc struct {
 	Role            string   `json:"role"`
 	About           string   `json:"about,omitempty"`
 	PreferredGenres []string `json:"preferredGenres,omitempty"`
+	Gender          string   `json:"gender,omitempty"`     // demografía de ml-100k/ml-1m (M/F)
+	Age             int      `json:"age,omitempty"`        // ml-100k: edad; ml-1m: código de rango (1, 18, 25...)
+	Occupation      string   `json:"occupation,omitempty"` // ml-100k/ml-1m
+	Zip             string   `json:"zip,omitempty"`        // código postal de ml-100k/ml-1m
 	CreatedAt       string   `json:"createdAt"`
 	UpdatedAt       string   `json:"updatedAt"`
 }
+
+// Demographics son los datos demográficos de un usuario (u.user de ml-100k, users.dat de ml-1m)
+type Demographics struct {
+	Gender     string
+	Age        int
+	Occupation string
+	Zip        string
+}
@@ -0,0 +1,283 @@
+package processors
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"pc4_etl/internal/models"
+)
+
+// MovieSink recibe cada documento de película generado por ProcessMovies
+type MovieSink interface {
+	AddMovie(doc models.MovieDoc) error
+}
+
+// SimilaritySink recibe cada documento de similitud generado por ProcessSimilarities
+type SimilaritySink interface {
+	AddSimilarity(doc models.SimilarityDoc) error
+}
+
+// graphFile es un CSV de neo4j-admin import (nodos o relaciones)
+type graphFile struct {
+	name  string
+	label string // label del nodo o tipo de relación
+	nodes bool
+	f     *os.File
+	w     *csv.Writer
+	rows  int
+}
+
+// GraphExporter genera los CSV de nodos y relaciones para `neo4j-admin database import full`:
+// (:User)-[:RATED]->(:Movie), (:Movie)-[:SIMILAR_TO]->(:Movie),
+// (:Movie)-[:IN_GENRE]->(:Genre) y (:Movie)-[:TAGGED]->(:Tag)
+type GraphExporter struct {
+	outDir string
+	files  map[string]*graphFile
+	order  []string
+	users  map[int]struct{}
+	genres map[string]struct{}
+	tags   map[string]struct{}
+	movies map[int]struct{}
+}
+
+// NewGraphExporter crea el directorio de salida y los CSV con sus cabeceras
+func NewGraphExporter(outDir string) (*GraphExporter, error) {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, err
+	}
+	g := &GraphExporter{
+		outDir: outDir,
+		files:  make(map[string]*graphFile),
+		users:  make(map[int]struct{}),
+		genres: make(map[string]struct{}),
+		tags:   make(map[string]struct{}),
+		movies: make(map[int]struct{}),
+	}
+
+	specs := []struct {
+		name, label string
+		nodes       bool
+		header      []string
+	}{
+		{"movies.csv", "Movie", true, []string{"movieId:ID(Movie)", "iIdx:int", "title", "year:int", "posterUrl"}},
+		{"users.csv", "User", true, []string{"userId:ID(User)"}},
+		{"genres.csv", "Genre", true, []string{"name:ID(Genre)"}},
+		{"tags.csv", "Tag", true, []string{"name:ID(Tag)"}},
+		{"rated.csv", "RATED", false, []string{":START_ID(User)", ":END_ID(Movie)", "rating:float", "ts:long"}},
+		{"similar_to.csv", "SIMILAR_TO", false, []string{":START_ID(Movie)", ":END_ID(Movie)", "sim:float"}},
+		{"in_genre.csv", "IN_GENRE", false, []string{":START_ID(Movie)", ":END_ID(Genre)"}},
+		{"tagged.csv", "TAGGED", false, []string{":START_ID(Movie)", ":END_ID(Tag)", "relevance:float"}},
+	}
+	for _, spec := range specs {
+		f, err := os.Create(filepath.Join(outDir, spec.name))
+		if err != nil {
+			g.closeFiles()
+			return nil, err
+		}
+		gf := &graphFile{name: spec.name, label: spec.label, nodes: spec.nodes, f: f, w: csv.NewWriter(bufio.NewWriter(f))}
+		g.files[spec.name] = gf
+		g.order = append(g.order, spec.name)
+		if err := gf.w.Write(spec.header); err != nil {
+			g.closeFiles()
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// write agrega una fila al CSV indicado
+func (g *GraphExporter) write(name string, row ...string) error {
+	gf := g.files[name]
+	gf.rows++
+	return gf.w.Write(row)
+}
+
+// AddMovie escribe el nodo Movie y sus relaciones IN_GENRE y TAGGED (genome tags)
+func (g *GraphExporter) AddMovie(doc models.MovieDoc) error {
+	if _, seen := g.movies[doc.MovieID]; seen {
+		return nil
+	}
+	g.movies[doc.MovieID] = struct{}{}
+
+	iIdx, year, poster := "", "", ""
+	if doc.IIdx != nil {
+		iIdx = strconv.Itoa(*doc.IIdx)
+	}
+	if doc.Year != nil {
+		year = strconv.Itoa(*doc.Year)
+	}
+	if doc.ExternalData != nil {
+		poster = doc.ExternalData.PosterURL
+	}
+	mid := strconv.Itoa(doc.MovieID)
+	if err := g.write("movies.csv", mid, iIdx, doc.Title, year, poster); err != nil {
+		return err
+	}
+
+	for _, genre := range doc.Genres {
+		g.genres[genre] = struct{}{}
+		if err := g.write("in_genre.csv", mid, genre); err != nil {
+			return err
+		}
+	}
+	for _, gt := range doc.GenomeTags {
+		g.tags[gt.Tag] = struct{}{}
+		if err := g.write("tagged.csv", mid, gt.Tag, strconv.FormatFloat(gt.Relevance, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSimilarity escribe las relaciones SIMILAR_TO de un documento de similitudes
+func (g *GraphExporter) AddSimilarity(doc models.SimilarityDoc) error {
+	if doc.MovieID <= 0 {
+		return nil
+	}
+	from := strconv.Itoa(doc.MovieID)
+	for _, n := range doc.Neighbors {
+		if n.MovieID <= 0 {
+			continue
+		}
+		if err := g.write("similar_to.csv", from, strconv.Itoa(n.MovieID), strconv.FormatFloat(n.Sim, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ratings devuelve un RatingSink que escribe las relaciones RATED
+// (su Close no cierra el exportador, que sigue recibiendo películas y similitudes)
+func (g *GraphExporter) Ratings() RatingSink {
+	return graphRatingSink{g}
+}
+
+type graphRatingSink struct {
+	g *GraphExporter
+}
+
+func (s graphRatingSink) Add(doc models.RatingDoc) error {
+	s.g.users[doc.UserID] = struct{}{}
+	return s.g.write("rated.csv",
+		strconv.Itoa(doc.UserID),
+		strconv.Itoa(doc.MovieID),
+		strconv.FormatFloat(doc.Rating, 'f', -1, 64),
+		strconv.FormatInt(doc.Timestamp, 10))
+}
+
+func (s graphRatingSink) Close() error {
+	return nil
+}
+
+// Close escribe los nodos acumulados (User, Genre, Tag), el script de importación y cierra los archivos.
+// Las relaciones hacia películas sin nodo (ej: ratings de películas fuera de movies.csv) se
+// descartan en la importación mediante --skip-bad-relationships.
+func (g *GraphExporter) Close() error {
+	userIds := make([]int, 0, len(g.users))
+	for uid := range g.users {
+		userIds = append(userIds, uid)
+	}
+	sort.Ints(userIds)
+	for _, uid := range userIds {
+		if err := g.write("users.csv", strconv.Itoa(uid)); err != nil {
+			g.closeFiles()
+			return err
+		}
+	}
+	for _, name := range sortedKeys(g.genres) {
+		if err := g.write("genres.csv", name); err != nil {
+			g.closeFiles()
+			return err
+		}
+	}
+	for _, name := range sortedKeys(g.tags) {
+		if err := g.write("tags.csv", name); err != nil {
+			g.closeFiles()
+			return err
+		}
+	}
+
+	if err := g.closeFiles(); err != nil {
+		return err
+	}
+	return g.writeImportScript()
+}
+
+// Counts devuelve el número de filas escritas por archivo (sin cabecera)
+func (g *GraphExporter) Counts() map[string]int {
+	counts := make(map[string]int, len(g.files))
+	for name, gf := range g.files {
+		counts[name] = gf.rows
+	}
+	return counts
+}
+
+// closeFiles vacía y cierra todos los CSV abiertos
+func (g *GraphExporter) closeFiles() error {
+	var firstErr error
+	for _, name := range g.order {
+		gf := g.files[name]
+		if gf.f == nil {
+			continue
+		}
+		gf.w.Flush()
+		if err := gf.w.Error(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := gf.f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		gf.f = nil
+	}
+	return firstErr
+}
+
+// writeImportScript genera neo4j-import.sh con el comando neo4j-admin correspondiente
+func (g *GraphExporter) writeImportScript() error {
+	path := filepath.Join(g.outDir, "neo4j-import.sh")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintln(w, "#!/usr/bin/env bash")
+	fmt.Fprintln(w, "# Generado por el ETL - importación masiva a Neo4j (la base de datos destino debe estar vacía)")
+	fmt.Fprintln(w, "# Uso: ./neo4j-import.sh [database] [graph_dir]")
+	fmt.Fprintln(w, "# NEO4J_OVERWRITE=true reemplaza una base de datos existente (se pierden sus datos)")
+	fmt.Fprintln(w, "set -euo pipefail")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "DATABASE=\"${1:-neo4j}\"")
+	fmt.Fprintln(w, "GRAPH_DIR=\"${2:-$(cd \"$(dirname \"$0\")\" && pwd)}\"")
+	fmt.Fprintln(w, "OVERWRITE=\"${NEO4J_OVERWRITE:-false}\"")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "neo4j-admin database import full \\")
+	for _, name := range g.order {
+		gf := g.files[name]
+		kind := "--relationships"
+		if gf.nodes {
+			kind = "--nodes"
+		}
+		fmt.Fprintf(w, "  %s=%s=\"$GRAPH_DIR/%s\" \\\n", kind, gf.label, gf.name)
+	}
+	fmt.Fprintln(w, "  --skip-bad-relationships=true \\")
+	fmt.Fprintln(w, "  --overwrite-destination=\"$OVERWRITE\" \\")
+	fmt.Fprintln(w, "  \"$DATABASE\"")
+	return w.Flush()
+}
+
+// sortedKeys devuelve las claves de un set de strings ordenadas
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
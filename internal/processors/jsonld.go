@@ -0,0 +1,154 @@
+package processors
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"pc4_etl/internal/models"
+)
+
+// Modos de salida de JSON-LD
+const (
+	JSONLDNDJSON = "ndjson" // una línea por película en movies.jsonld.ndjson
+	JSONLDFiles  = "files"  // un archivo <movieId>.jsonld por película
+)
+
+// JSONLDWriter convierte cada MovieDoc en un schema.org Movie (JSON-LD)
+type JSONLDWriter struct {
+	mode      string
+	outDir    string
+	f         *os.File
+	w         *bufio.Writer
+	bestScore float64
+	minScore  float64
+	written   int
+}
+
+// NewJSONLDWriter crea el escritor en modo "ndjson" (outPath es el archivo) o "files" (outPath es un directorio).
+// bestScore/minScore definen la escala publicada en aggregateRating (la de --rating-scale; MovieLens: 5 y 0.5).
+func NewJSONLDWriter(mode, outPath string, bestScore, minScore float64) (*JSONLDWriter, error) {
+	jw := &JSONLDWriter{mode: mode, bestScore: bestScore, minScore: minScore}
+	switch mode {
+	case JSONLDNDJSON:
+		f, err := os.Create(outPath)
+		if err != nil {
+			return nil, err
+		}
+		jw.f = f
+		jw.w = bufio.NewWriter(f)
+	case JSONLDFiles:
+		if err := os.MkdirAll(outPath, 0o755); err != nil {
+			return nil, err
+		}
+		jw.outDir = outPath
+	default:
+		return nil, fmt.Errorf("modo JSON-LD desconocido: %s (use %s o %s)", mode, JSONLDNDJSON, JSONLDFiles)
+	}
+	return jw, nil
+}
+
+// AddMovie escribe el JSON-LD de la película
+func (jw *JSONLDWriter) AddMovie(doc models.MovieDoc) error {
+	ld := jw.build(doc)
+	switch jw.mode {
+	case JSONLDNDJSON:
+		b, err := json.Marshal(ld)
+		if err != nil {
+			return err
+		}
+		jw.w.Write(b)
+		jw.w.WriteByte('\n')
+	case JSONLDFiles:
+		b, err := json.MarshalIndent(ld, "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(jw.outDir, strconv.Itoa(doc.MovieID)+".jsonld")
+		if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	jw.written++
+	return nil
+}
+
+// Close vacía y cierra el archivo NDJSON (no hace nada en modo "files")
+func (jw *JSONLDWriter) Close() error {
+	if jw.f == nil {
+		return nil
+	}
+	if err := jw.w.Flush(); err != nil {
+		jw.f.Close()
+		return err
+	}
+	return jw.f.Close()
+}
+
+// Count devuelve el número de películas escritas
+func (jw *JSONLDWriter) Count() int {
+	return jw.written
+}
+
+// build mapea los campos del MovieDoc a las propiedades de schema.org Movie
+func (jw *JSONLDWriter) build(doc models.MovieDoc) models.JSONLDMovie {
+	ld := models.JSONLDMovie{
+		Context:       "https://schema.org",
+		Type:          "Movie",
+		Identifier:    strconv.Itoa(doc.MovieID),
+		Name:          doc.Title,
+		AlternateName: doc.AlternateTitles,
+		Genre:         doc.Genres,
+	}
+	if doc.Year != nil {
+		ld.DatePublished = strconv.Itoa(*doc.Year)
+	}
+
+	if ext := doc.ExternalData; ext != nil {
+		ld.Description = ext.Overview
+		ld.Image = ext.PosterURL
+		if ext.Runtime > 0 {
+			ld.Duration = fmt.Sprintf("PT%dM", ext.Runtime)
+		}
+		if ext.Director != "" {
+			ld.Director = &models.JSONLDPerson{Type: "Person", Name: ext.Director}
+		}
+		for _, member := range ext.Cast {
+			ld.Actor = append(ld.Actor, models.JSONLDPerson{
+				Type:  "Person",
+				Name:  member.Name,
+				Image: member.ProfileURL,
+			})
+		}
+	}
+
+	// Google exige ratingCount > 0 para publicar aggregateRating
+	if stats := doc.RatingStats; stats != nil && stats.Count > 0 {
+		ld.AggregateRating = &models.JSONLDAggregateRating{
+			Type:        "AggregateRating",
+			RatingValue: roundTo(stats.Average, 2),
+			RatingCount: stats.Count,
+			BestRating:  jw.bestScore,
+			WorstRating: jw.minScore,
+		}
+	}
+
+	if links := doc.Links; links != nil {
+		for _, url := range []string{links.IMDB, links.TMDB, links.Movielens} {
+			if url != "" {
+				ld.SameAs = append(ld.SameAs, url)
+			}
+		}
+	}
+
+	return ld
+}
+
+// roundTo redondea a n decimales
+func roundTo(v float64, n int) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'f', n, 64), 64)
+	return f
+}
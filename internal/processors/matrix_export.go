@@ -0,0 +1,307 @@
+package processors
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"pc4_etl/internal/mappers"
+	"pc4_etl/internal/models"
+)
+
+// MatrixMeta describe la matriz exportada (matrix/ratings_csr.json)
+type MatrixMeta struct {
+	Rows         int               `json:"rows"`
+	Cols         int               `json:"cols"`
+	NNZ          int               `json:"nnz"`
+	RowIndex     string            `json:"rowIndex"`
+	ColIndex     string            `json:"colIndex"`
+	IndexBase    int               `json:"indexBase"` // la fila r es uIdx = r + indexBase y la columna c es iIdx = c + indexBase
+	ByteOrder    string            `json:"byteOrder"`
+	Files        map[string]string `json:"files"`
+	DTypes       map[string]string `json:"dtypes"`
+	IndexFiles   map[string]string `json:"indexFiles"`
+	MatrixMarket string            `json:"matrixMarket"`
+	Unmapped     int               `json:"unmapped,omitempty"`   // ratings omitidos por userId/movieId sin uIdx/iIdx válido
+	Duplicates   int               `json:"duplicates,omitempty"` // pares (uIdx, iIdx) repetidos: se conserva el último
+}
+
+// MatrixExporter acumula los ratings como tripletas (uIdx, iIdx, rating) y al cerrar
+// escribe la matriz en formato MatrixMarket y CSR binario (indptr/indices/data)
+type MatrixExporter struct {
+	outDir     string
+	userMapper *mappers.IDMapper
+	itemMapper *mappers.IDMapper
+	rows       []int32
+	cols       []int32
+	vals       []float32
+	unmapped   int
+	meta       MatrixMeta
+}
+
+// NewMatrixExporter crea el exportador; los archivos se escriben en outDir al llamar a Close
+func NewMatrixExporter(outDir string, userMapper, itemMapper *mappers.IDMapper) (*MatrixExporter, error) {
+	if userMapper == nil || itemMapper == nil {
+		return nil, fmt.Errorf("la exportación de matriz requiere mapeos de usuarios e items")
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, err
+	}
+	return &MatrixExporter{
+		outDir:     outDir,
+		userMapper: userMapper,
+		itemMapper: itemMapper,
+	}, nil
+}
+
+// Add registra un rating usando uIdx/iIdx de los mapeadores; no asigna índices nuevos (los
+// mapeos se guardan después), así que los ratings de usuarios o películas sin índice, o con un
+// índice menor que --index-base (ver "mappings check"), se omiten
+func (m *MatrixExporter) Add(doc models.RatingDoc) error {
+	uIdx := m.userMapper.Get(doc.UserID)
+	iIdx := m.itemMapper.Get(doc.MovieID)
+	if uIdx < m.userMapper.Base() || iIdx < m.itemMapper.Base() {
+		m.unmapped++
+		return nil
+	}
+	// La matriz siempre empieza en la fila/columna 0, independientemente de --index-base
+	m.rows = append(m.rows, int32(uIdx-m.userMapper.Base()))
+	m.cols = append(m.cols, int32(iIdx-m.itemMapper.Base()))
+	m.vals = append(m.vals, float32(doc.Rating))
+	return nil
+}
+
+// Close ordena las tripletas por fila/columna y escribe todos los archivos de la matriz
+func (m *MatrixExporter) Close() error {
+	userMap := m.userMapper.GetMapping()
+	itemMap := m.itemMapper.GetMapping()
+	base := m.itemMapper.Base()
+	nRows := maxIndex(userMap) - m.userMapper.Base() + 1
+	nCols := maxIndex(itemMap) - base + 1
+
+	indptr, indices, data, duplicates := m.toCSR(nRows, nCols)
+
+	m.meta = MatrixMeta{
+		Rows:      nRows,
+		Cols:      nCols,
+		NNZ:       len(data),
+		RowIndex:  "uIdx",
+		ColIndex:  "iIdx",
+		IndexBase: base,
+		ByteOrder: "little",
+		Files: map[string]string{
+			"indptr":  "ratings_csr_indptr.bin",
+			"indices": "ratings_csr_indices.bin",
+			"data":    "ratings_csr_data.bin",
+		},
+		DTypes: map[string]string{
+			"indptr":  "int64",
+			"indices": "int32",
+			"data":    "float32",
+		},
+		IndexFiles: map[string]string{
+			"rows": "row_index.csv",
+			"cols": "col_index.csv",
+		},
+		MatrixMarket: "ratings.mtx",
+		Unmapped:     m.unmapped,
+		Duplicates:   duplicates,
+	}
+
+	if err := writeBinary(filepath.Join(m.outDir, m.meta.Files["indptr"]), indptr); err != nil {
+		return err
+	}
+	if err := writeBinary(filepath.Join(m.outDir, m.meta.Files["indices"]), indices); err != nil {
+		return err
+	}
+	if err := writeBinary(filepath.Join(m.outDir, m.meta.Files["data"]), data); err != nil {
+		return err
+	}
+	if err := writeMatrixMarket(filepath.Join(m.outDir, m.meta.MatrixMarket), nRows, nCols, base, indptr, indices, data); err != nil {
+		return err
+	}
+	if err := writeIndexFile(filepath.Join(m.outDir, m.meta.IndexFiles["rows"]), "uIdx", "userId", userMap); err != nil {
+		return err
+	}
+	if err := writeIndexFile(filepath.Join(m.outDir, m.meta.IndexFiles["cols"]), "iIdx", "movieId", itemMap); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(m.meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.outDir, "ratings_csr.json"), append(b, '\n'), 0o644)
+}
+
+// Meta devuelve la descripción de la matriz escrita (válida después de Close)
+func (m *MatrixExporter) Meta() MatrixMeta {
+	return m.meta
+}
+
+// toCSR convierte las tripletas a CSR con counting sort por fila y columnas ordenadas dentro de cada
+// fila. Las tripletas fuera de nRows x nCols se omiten (se suman a unmapped) y los pares (fila,
+// columna) repetidos se reducen a uno con el último valor agregado, igual que --duplicates
+// keep-last; devuelve también cuántos repetidos se descartaron
+func (m *MatrixExporter) toCSR(nRows, nCols int) ([]int64, []int32, []float32, int) {
+	kept := 0
+	for k, r := range m.rows {
+		if r < 0 || int(r) >= nRows || m.cols[k] < 0 || int(m.cols[k]) >= nCols {
+			m.unmapped++
+			continue
+		}
+		m.rows[kept], m.cols[kept], m.vals[kept] = r, m.cols[k], m.vals[k]
+		kept++
+	}
+	m.rows, m.cols, m.vals = m.rows[:kept], m.cols[:kept], m.vals[:kept]
+
+	indptr := make([]int64, nRows+1)
+	for _, r := range m.rows {
+		indptr[r+1]++
+	}
+	for r := 0; r < nRows; r++ {
+		indptr[r+1] += indptr[r]
+	}
+
+	indices := make([]int32, len(m.cols))
+	data := make([]float32, len(m.vals))
+	next := make([]int64, nRows)
+	copy(next, indptr[:nRows])
+	for k, r := range m.rows {
+		pos := next[r]
+		indices[pos] = m.cols[k]
+		data[pos] = m.vals[k]
+		next[r]++
+	}
+
+	// El counting sort conserva el orden de llegada dentro de cada fila y sort.Stable lo mantiene
+	// entre columnas iguales, así que la última aparición de un par queda al final de su grupo
+	var out int64
+	for r := 0; r < nRows; r++ {
+		start, end := indptr[r], indptr[r+1]
+		if end-start > 1 {
+			sort.Stable(rowSorter{indices[start:end], data[start:end]})
+		}
+		indptr[r] = out
+		for k := start; k < end; k++ {
+			if k+1 < end && indices[k+1] == indices[k] {
+				continue
+			}
+			indices[out] = indices[k]
+			data[out] = data[k]
+			out++
+		}
+	}
+	indptr[nRows] = out
+	duplicates := len(indices) - int(out)
+
+	// Liberar las tripletas: ya no se necesitan
+	m.rows, m.cols, m.vals = nil, nil, nil
+	return indptr, indices[:out], data[:out], duplicates
+}
+
+// rowSorter ordena una fila CSR por índice de columna manteniendo los valores alineados
+type rowSorter struct {
+	indices []int32
+	data    []float32
+}
+
+func (s rowSorter) Len() int           { return len(s.indices) }
+func (s rowSorter) Less(i, j int) bool { return s.indices[i] < s.indices[j] }
+func (s rowSorter) Swap(i, j int) {
+	s.indices[i], s.indices[j] = s.indices[j], s.indices[i]
+	s.data[i], s.data[j] = s.data[j], s.data[i]
+}
+
+// maxIndex devuelve el mayor índice de un mapeo (o -1 si está vacío)
+func maxIndex(mapping map[int]int) int {
+	max := -1
+	for _, idx := range mapping {
+		if idx > max {
+			max = idx
+		}
+	}
+	return max
+}
+
+// writeBinary escribe un arreglo numérico en little-endian
+func writeBinary(path string, values interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := binary.Write(w, binary.LittleEndian, values); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// writeMatrixMarket escribe la matriz en formato coordinate (índices base 1 según el estándar)
+func writeMatrixMarket(path string, nRows, nCols, indexBase int, indptr []int64, indices []int32, data []float32) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintln(w, "%%MatrixMarket matrix coordinate real general")
+	if indexBase == 1 {
+		fmt.Fprintln(w, "% filas = uIdx (row_index.csv), columnas = iIdx (col_index.csv)")
+	} else {
+		fmt.Fprintln(w, "% filas = uIdx + 1 (row_index.csv), columnas = iIdx + 1 (col_index.csv)")
+	}
+	fmt.Fprintf(w, "%d %d %d\n", nRows, nCols, len(data))
+	for r := 0; r < nRows; r++ {
+		for k := indptr[r]; k < indptr[r+1]; k++ {
+			w.WriteString(strconv.Itoa(r + 1))
+			w.WriteByte(' ')
+			w.WriteString(strconv.Itoa(int(indices[k]) + 1))
+			w.WriteByte(' ')
+			w.WriteString(strconv.FormatFloat(float64(data[k]), 'g', -1, 32))
+			w.WriteByte('\n')
+		}
+	}
+	return w.Flush()
+}
+
+// writeIndexFile escribe la correspondencia índice -> id original ordenada por índice
+func writeIndexFile(path, idxHeader, idHeader string, mapping map[int]int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	type kv struct {
+		idx int
+		id  int
+	}
+	entries := make([]kv, 0, len(mapping))
+	for id, idx := range mapping {
+		entries = append(entries, kv{idx, id})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].idx < entries[j].idx
+	})
+
+	w := csv.NewWriter(bufio.NewWriter(f))
+	if err := w.Write([]string{idxHeader, idHeader}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := w.Write([]string{strconv.Itoa(e.idx), strconv.Itoa(e.id)}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,68 @@
+package processors
+
+import (
+	"reflect"
+	"testing"
+
+	"pc4_etl/internal/mappers"
+	"pc4_etl/internal/models"
+)
+
+func TestMatrixExporterCSR(t *testing.T) {
+	// --index-base 1 con un movieId en el índice 0 (inválido): sus ratings quedan fuera
+	users := mappers.NewIDMapper(map[int]int{10: 1, 20: 2}, 1)
+	items := mappers.NewIDMapper(map[int]int{100: 1, 200: 2, 300: 0}, 1)
+	m := &MatrixExporter{userMapper: users, itemMapper: items}
+
+	for _, doc := range []models.RatingDoc{
+		{UserID: 20, MovieID: 200, Rating: 1},
+		{UserID: 10, MovieID: 200, Rating: 4},
+		{UserID: 10, MovieID: 100, Rating: 2},
+		{UserID: 10, MovieID: 200, Rating: 5}, // repetido: gana el último
+		{UserID: 10, MovieID: 300, Rating: 3}, // iIdx 0 < base
+		{UserID: 99, MovieID: 100, Rating: 3}, // userId sin uIdx
+	} {
+		if err := m.Add(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m.unmapped != 2 {
+		t.Errorf("unmapped = %d, want 2", m.unmapped)
+	}
+	// El mapeo de usuarios no debe crecer con los ratings exportados
+	if users.Count() != 2 {
+		t.Errorf("userMapper tiene %d ids, want 2", users.Count())
+	}
+
+	indptr, indices, data, duplicates := m.toCSR(2, 2)
+	if want := []int64{0, 2, 3}; !reflect.DeepEqual(indptr, want) {
+		t.Errorf("indptr = %v, want %v", indptr, want)
+	}
+	if want := []int32{0, 1, 1}; !reflect.DeepEqual(indices, want) {
+		t.Errorf("indices = %v, want %v", indices, want)
+	}
+	if want := []float32{2, 5, 1}; !reflect.DeepEqual(data, want) {
+		t.Errorf("data = %v, want %v", data, want)
+	}
+	if duplicates != 1 {
+		t.Errorf("duplicates = %d, want 1", duplicates)
+	}
+}
+
+func TestMatrixExporterCSROutOfRange(t *testing.T) {
+	m := &MatrixExporter{
+		rows: []int32{0, 3, 1, -1},
+		cols: []int32{1, 0, 2, 0},
+		vals: []float32{1, 2, 3, 4},
+	}
+	indptr, indices, data, duplicates := m.toCSR(2, 2)
+	if want := []int64{0, 1, 1}; !reflect.DeepEqual(indptr, want) {
+		t.Errorf("indptr = %v, want %v", indptr, want)
+	}
+	if len(indices) != 1 || indices[0] != 1 || data[0] != 1 || duplicates != 0 {
+		t.Errorf("indices = %v, data = %v, duplicates = %d", indices, data, duplicates)
+	}
+	if m.unmapped != 3 {
+		t.Errorf("unmapped = %d, want 3", m.unmapped)
+	}
+}
@@ -3,10 +3,10 @@ package processors
 import (
 	"bufio"
 	"crypto/rand"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	mathrand "math/rand"
 	"os"
@@ -17,8 +17,10 @@ import (
 	"time"
 
 	"pc4_etl/internal/external"
+	"pc4_etl/internal/inputs"
 	"pc4_etl/internal/mappers"
 	"pc4_etl/internal/models"
+	"pc4_etl/internal/taxonomy"
 	"pc4_etl/internal/utils"
 
 	"github.com/jaswdr/faker"
@@ -30,26 +32,6 @@ func isoNow() string {
 	return time.Now().UTC().Format(time.RFC3339)
 }
 
-// parseTitleAndYear extrae el título y año de una cadena como "Movie Title (2020)"
-func parseTitleAndYear(raw string, yearRe *regexp.Regexp) (string, *int) {
-	raw = strings.TrimSpace(raw)
-	m := yearRe.FindStringSubmatch(raw)
-	if len(m) == 2 {
-		y, err := strconv.Atoi(m[1])
-		if err == nil {
-			// remove last occurrence of (YYYY)
-			idx := strings.LastIndex(raw, "(")
-			if idx > 0 {
-				title := strings.TrimSpace(raw[:idx])
-				return title, &y
-			}
-			return strings.TrimSpace(raw), &y
-		}
-	}
-	// fallback: no year
-	return raw, nil
-}
-
 // generateRandomPassword genera un password de 10 dígitos aleatorios
 func generateRandomPassword() (string, error) {
 	password := ""
@@ -72,22 +54,17 @@ func hashPassword(password string) (string, error) {
 	return string(bytes), nil
 }
 
-// ProcessUsers genera users.ndjson con passwords hasheados
-func ProcessUsers(ratingsPath, outPath, passwordLogPath string, userMapper *mappers.IDMapper, hashPasswords bool, allGenres []string) (int, error) {
+// ProcessUsers genera users.ndjson con passwords hasheados. Los usuarios presentes en existingUsers
+// (ej: reconstruidos desde un users.ndjson previo) conservan sus datos y password; demographics
+// (ml-100k/ml-1m, puede ser nil) agrega género, edad, ocupación y código postal.
+func ProcessUsers(ratingsPath, outPath, passwordLogPath string, userMapper *mappers.IDMapper, hashPasswords bool, allGenres []string, existingUsers map[int]*models.UserDoc, demographics map[int]models.Demographics) (int, error) {
 	// Primero, leer ratings para obtener todos los usuarios únicos
-	f, err := os.Open(ratingsPath)
+	r, err := inputs.OpenCSV(ratingsPath, "ratings", "userId")
 	if err != nil {
 		return 0, err
 	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
-
-	// skip header
-	if _, err := r.Read(); err != nil {
-		return 0, err
-	}
+	defer r.Close()
+	userCol := r.Index("userId")
 
 	users := make(map[int]struct{})
 	for {
@@ -95,10 +72,10 @@ func ProcessUsers(ratingsPath, outPath, passwordLogPath string, userMapper *mapp
 		if err == io.EOF {
 			break
 		}
-		if err != nil || len(rec) < 1 {
+		if err != nil {
 			continue
 		}
-		uid, _ := strconv.Atoi(rec[0])
+		uid, _ := strconv.Atoi(inputs.Field(rec, userCol))
 		if uid > 0 {
 			users[uid] = struct{}{}
 		}
@@ -140,6 +117,20 @@ func ProcessUsers(ratingsPath, outPath, passwordLogPath string, userMapper *mapp
 	written := 0
 
 	for _, uid := range userIds {
+		// Usuario ya existente: solo se actualizan uIdx y updatedAt (no se conoce su password)
+		if existing, ok := existingUsers[uid]; ok {
+			doc := *existing
+			uIdx := userMapper.GetOrCreate(uid)
+			doc.UIdx = &uIdx
+			applyDemographics(&doc, demographics)
+			doc.UpdatedAt = now
+			b, _ := json.Marshal(doc)
+			w.Write(b)
+			w.WriteByte('\n')
+			written++
+			continue
+		}
+
 		// Generar nombre y apellido con faker
 		firstName, lastName := utils.GenerateRandomName(fake)
 
@@ -189,6 +180,7 @@ func ProcessUsers(ratingsPath, outPath, passwordLogPath string, userMapper *mapp
 		// Agregar uIdx usando el mapper dinámico
 		uIdx := userMapper.GetOrCreate(uid)
 		doc.UIdx = &uIdx
+		applyDemographics(&doc, demographics)
 
 		// Escribir NDJSON
 		b, _ := json.Marshal(doc)
@@ -209,8 +201,17 @@ func ProcessUsers(ratingsPath, outPath, passwordLogPath string, userMapper *mapp
 	return written, nil
 }
 
-// ProcessSimilarities genera similarities.ndjson
-func ProcessSimilarities(outPath string, similarities map[int][]models.Neighbor, itemMapper *mappers.IDMapper) (int, error) {
+// applyDemographics copia al usuario los datos demográficos del dataset (si los hay)
+func applyDemographics(doc *models.UserDoc, demographics map[int]models.Demographics) {
+	d, ok := demographics[doc.UserID]
+	if !ok {
+		return
+	}
+	doc.Gender, doc.Age, doc.Occupation, doc.Zip = d.Gender, d.Age, d.Occupation, d.Zip
+}
+
+// ProcessSimilarities genera similarities.ndjson y entrega cada documento a los sinks
+func ProcessSimilarities(outPath string, similarities map[int][]models.Neighbor, itemMapper *mappers.IDMapper, sinks ...SimilaritySink) (int, error) {
 	// Crear reverse map: iIdx -> movieId
 	itemMap := itemMapper.GetMapping()
 	reverseMap := make(map[int]int)
@@ -252,20 +253,28 @@ func ProcessSimilarities(outPath string, similarities map[int][]models.Neighbor,
 		w.Write(b)
 		w.WriteByte('\n')
 		written++
+
+		for _, sink := range sinks {
+			if err := sink.AddSimilarity(doc); err != nil {
+				return written, err
+			}
+		}
 	}
 
 	return written, nil
 }
 
-// ProcessMovies genera movies.ndjson enriquecido con datos externos
-func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTags map[int][]models.GenomeTag, userTags map[int][]string, ratingStats map[int]*models.RatingStats, itemMapper *mappers.IDMapper, topGenomeTags int, tmdbClient *external.TMDBClient, fetchExternal bool, yearRe *regexp.Regexp) (int, error) {
-	f, err := os.Open(inPath)
+// ProcessMovies genera movies.ndjson enriquecido con datos externos.
+// Si topSimilar > 0 se embeben los N vecinos más similares (similarities indexado por iIdx),
+// lo que requiere mantener los documentos en memoria hasta resolver todos los títulos.
+// Cada documento escrito se entrega además a los sinks (ej: exportación a grafo).
+func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTags map[int][]models.GenomeTag, userTags map[int][]string, ratingStats map[int]*models.RatingStats, itemMapper *mappers.IDMapper, topGenomeTags int, tmdbClient *external.TMDBClient, fetchExternal bool, cachedExternal map[int]*models.ExternalData, yearRe *regexp.Regexp, genreTaxonomy *taxonomy.Taxonomy, genresFormat string, similarities map[int][]models.Neighbor, topSimilar int, sinks ...MovieSink) (int, error) {
+	r, err := inputs.OpenCSV(inPath, "movies", "movieId", "title", "genres")
 	if err != nil {
 		return 0, err
 	}
-	defer f.Close()
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
+	defer r.Close()
+	movieCol, titleCol, genresCol := r.Index("movieId"), r.Index("title"), r.Index("genres")
 
 	// open output
 	of, err := os.Create(outPath)
@@ -276,20 +285,11 @@ func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTa
 	w := bufio.NewWriter(of)
 	defer w.Flush()
 
-	// read header
-	header, err := r.Read()
-	if err != nil {
-		return 0, err
-	}
-	idx := map[string]int{}
-	for i, h := range header {
-		idx[h] = i
-	}
-
 	written := 0
 	now := isoNow()
 	fetchedCount := 0
 	errorCount := 0
+	var pending []models.MovieDoc
 
 	for {
 		rec, err := r.Read()
@@ -300,27 +300,11 @@ func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTa
 			// skip malformed
 			continue
 		}
-		// guard indexes
-		mid := 0
-		if v, ok := idx["movieId"]; ok && v < len(rec) {
-			mid, _ = strconv.Atoi(rec[v])
-		} else if len(rec) > 0 {
-			mid, _ = strconv.Atoi(rec[0])
-		}
-		titleRaw := ""
-		if v, ok := idx["title"]; ok && v < len(rec) {
-			titleRaw = rec[v]
-		} else if len(rec) > 1 {
-			titleRaw = rec[1]
-		}
-		genresRaw := ""
-		if v, ok := idx["genres"]; ok && v < len(rec) {
-			genresRaw = rec[v]
-		} else if len(rec) > 2 {
-			genresRaw = rec[2]
-		}
+		mid, _ := strconv.Atoi(inputs.Field(rec, movieCol))
+		titleRaw := inputs.Field(rec, titleCol)
+		genresRaw := inputs.Field(rec, genresCol)
 
-		title, year := parseTitleAndYear(titleRaw, yearRe)
+		parsed := parseTitle(titleRaw, yearRe)
 		genres := []string{}
 		if genresRaw != "" && genresRaw != "(no genres listed)" {
 			for _, g := range strings.Split(genresRaw, "|") {
@@ -331,13 +315,23 @@ func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTa
 			}
 		}
 
+		var genreObjects []models.Genre
+		if genreTaxonomy != nil {
+			genres, genreObjects = genreTaxonomy.Convert(genres, genresFormat)
+		}
+
 		doc := models.MovieDoc{
-			MovieID:   mid,
-			Title:     title,
-			Year:      year,
-			Genres:    genres,
-			CreatedAt: now,
-			UpdatedAt: now,
+			MovieID:         mid,
+			Title:           parsed.Title,
+			SortTitle:       parsed.SortTitle,
+			AlternateTitles: parsed.AlternateTitles,
+			Year:            parsed.Year,
+			EndYear:         parsed.EndYear,
+			Ongoing:         parsed.Ongoing,
+			Genres:          genres,
+			GenreObjects:    genreObjects,
+			CreatedAt:       now,
+			UpdatedAt:       now,
 		}
 
 		// Agregar iIdx usando el mapper dinámico
@@ -368,6 +362,11 @@ func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTa
 			doc.RatingStats = stats
 		}
 
+		// Datos de TMDB ya obtenidos en una exportación previa (se reemplazan si se vuelve a consultar)
+		if external, ok := cachedExternal[mid]; ok {
+			doc.ExternalData = external
+		}
+
 		// Fetch external data from TMDB if enabled
 		if fetchExternal && tmdbClient != nil && doc.Links != nil && doc.Links.TMDB != "" {
 			// Extract TMDB ID from URL
@@ -376,7 +375,7 @@ func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTa
 			if len(parts) > 0 {
 				tmdbID := parts[len(parts)-1]
 				if tmdbID != "" {
-					externalData, err := tmdbClient.FetchMovieData(tmdbID, title)
+					externalData, err := tmdbClient.FetchMovieData(tmdbID, parsed.Title)
 					if err != nil {
 						errorCount++
 						if errorCount%100 == 0 {
@@ -393,10 +392,45 @@ func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTa
 			}
 		}
 
+		if topSimilar > 0 {
+			pending = append(pending, doc)
+			continue
+		}
+
 		b, _ := json.Marshal(doc)
 		w.Write(b)
 		w.WriteByte('\n')
 		written++
+
+		for _, sink := range sinks {
+			if err := sink.AddMovie(doc); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	// Resolver vecinos contra las películas procesadas y escribir los documentos pendientes
+	if topSimilar > 0 {
+		byMovie := make(map[int]*models.MovieDoc, len(pending))
+		for i := range pending {
+			byMovie[pending[i].MovieID] = &pending[i]
+		}
+		for i := range pending {
+			doc := &pending[i]
+			if doc.IIdx != nil {
+				doc.Similar = embedSimilar(similarities[*doc.IIdx], byMovie, doc.MovieID, topSimilar)
+			}
+			b, _ := json.Marshal(doc)
+			w.Write(b)
+			w.WriteByte('\n')
+			written++
+
+			for _, sink := range sinks {
+				if err := sink.AddMovie(*doc); err != nil {
+					return written, err
+				}
+			}
+		}
 	}
 
 	if fetchExternal {
@@ -409,15 +443,66 @@ func ProcessMovies(inPath, outPath string, links map[int]*models.Links, genomeTa
 	return written, nil
 }
 
-// ProcessRatings genera ratings.ndjson
-func ProcessRatings(inPath, outPath string) (int, error) {
-	f, err := os.Open(inPath)
+// RatingSink recibe cada rating leído durante ProcessRatings para generar salidas derivadas
+type RatingSink interface {
+	Add(doc models.RatingDoc) error
+	Close() error
+}
+
+// embedSimilar construye la lista de vecinos desnormalizados (top N por similitud)
+// omitiendo los que no corresponden a ninguna película procesada
+func embedSimilar(neighbors []models.Neighbor, byMovie map[int]*models.MovieDoc, selfID, topN int) []models.SimilarMovie {
+	if len(neighbors) == 0 {
+		return nil
+	}
+	sorted := make([]models.Neighbor, len(neighbors))
+	copy(sorted, neighbors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Sim > sorted[j].Sim
+	})
+
+	similar := make([]models.SimilarMovie, 0, topN)
+	for _, n := range sorted {
+		if len(similar) >= topN {
+			break
+		}
+		target, ok := byMovie[n.MovieID]
+		if !ok || n.MovieID == selfID {
+			continue
+		}
+		s := models.SimilarMovie{
+			MovieID: n.MovieID,
+			Title:   target.Title,
+			Year:    target.Year,
+			Sim:     n.Sim,
+		}
+		if target.ExternalData != nil {
+			s.PosterURL = target.ExternalData.PosterURL
+		}
+		similar = append(similar, s)
+	}
+	return similar
+}
+
+// parseRating convierte el rating; si no es numérico devuelve NaN cuando hay un filtro que lo
+// valide (ver validation.RatingValidator) y 0 en caso contrario
+func parseRating(s string, validated bool) float64 {
+	rating, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil && validated {
+		return math.NaN()
+	}
+	return rating
+}
+
+// ProcessRatings genera ratings.ndjson y alimenta las salidas derivadas (sinks) en la misma pasada;
+// los ratings que filter descarta se omiten (filter nil = todos)
+func ProcessRatings(inPath, outPath string, filter models.RatingFilter, sinks ...RatingSink) (int, error) {
+	r, err := inputs.OpenCSV(inPath, "ratings", "userId", "movieId", "rating", "timestamp")
 	if err != nil {
 		return 0, err
 	}
-	defer f.Close()
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
+	defer r.Close()
+	userCol, movieCol, ratingCol, tsCol := r.Index("userId"), r.Index("movieId"), r.Index("rating"), r.Index("timestamp")
 
 	of, err := os.Create(outPath)
 	if err != nil {
@@ -427,15 +512,6 @@ func ProcessRatings(inPath, outPath string) (int, error) {
 	w := bufio.NewWriter(of)
 	defer w.Flush()
 
-	header, err := r.Read()
-	if err != nil {
-		return 0, err
-	}
-	idx := map[string]int{}
-	for i, h := range header {
-		idx[h] = i
-	}
-
 	written := 0
 	for {
 		rec, err := r.Read()
@@ -445,30 +521,10 @@ func ProcessRatings(inPath, outPath string) (int, error) {
 		if err != nil {
 			continue
 		}
-		uid := 0
-		mid := 0
-		rating := 0.0
-		ts := int64(0)
-		if v, ok := idx["userId"]; ok && v < len(rec) {
-			uid, _ = strconv.Atoi(rec[v])
-		} else if len(rec) > 0 {
-			uid, _ = strconv.Atoi(rec[0])
-		}
-		if v, ok := idx["movieId"]; ok && v < len(rec) {
-			mid, _ = strconv.Atoi(rec[v])
-		} else if len(rec) > 1 {
-			mid, _ = strconv.Atoi(rec[1])
-		}
-		if v, ok := idx["rating"]; ok && v < len(rec) {
-			rating, _ = strconv.ParseFloat(rec[v], 64)
-		} else if len(rec) > 2 {
-			rating, _ = strconv.ParseFloat(rec[2], 64)
-		}
-		if v, ok := idx["timestamp"]; ok && v < len(rec) {
-			ts, _ = strconv.ParseInt(rec[v], 10, 64)
-		} else if len(rec) > 3 {
-			ts, _ = strconv.ParseInt(rec[3], 10, 64)
-		}
+		uid, _ := strconv.Atoi(inputs.Field(rec, userCol))
+		mid, _ := strconv.Atoi(inputs.Field(rec, movieCol))
+		rating := parseRating(inputs.Field(rec, ratingCol), filter != nil)
+		ts, _ := strconv.ParseInt(inputs.Field(rec, tsCol), 10, 64)
 
 		doc := models.RatingDoc{
 			UserID:    uid,
@@ -476,10 +532,32 @@ func ProcessRatings(inPath, outPath string) (int, error) {
 			Rating:    rating,
 			Timestamp: ts,
 		}
-		b, _ := json.Marshal(doc)
+		if filter != nil && !filter.Keep(&doc) {
+			continue
+		}
+		// Sin validación, un rating infinito ("inf") no se puede escribir en JSON: se omite
+		if math.IsNaN(doc.Rating) || math.IsInf(doc.Rating, 0) {
+			continue
+		}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return written, fmt.Errorf("userId=%d movieId=%d: %w", doc.UserID, doc.MovieID, err)
+		}
 		w.Write(b)
 		w.WriteByte('\n')
 		written++
+
+		for _, sink := range sinks {
+			if err := sink.Add(doc); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			return written, err
+		}
 	}
 
 	return written, nil
@@ -0,0 +1,359 @@
+package processors
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pc4_etl/internal/models"
+)
+
+// Quantiles resume una distribución de conteos (ratings por usuario o por película)
+type Quantiles struct {
+	Min  int     `json:"min"`
+	P25  int     `json:"p25"`
+	P50  int     `json:"p50"`
+	P75  int     `json:"p75"`
+	P90  int     `json:"p90"`
+	P99  int     `json:"p99"`
+	Max  int     `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// Bucket es una entrada de un histograma o ranking de frecuencias
+type Bucket struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// RatingProfile son las métricas calculadas sobre los ratings escritos
+type RatingProfile struct {
+	Ratings        int       `json:"ratings"`
+	Users          int       `json:"users"`
+	Movies         int       `json:"movies"` // películas con al menos un rating
+	Mean           float64   `json:"mean"`
+	Histogram      []Bucket  `json:"histogram"`
+	PerUser        Quantiles `json:"perUser"`
+	PerMovie       Quantiles `json:"perMovie"`
+	Density        float64   `json:"density"`                  // ratings / (usuarios × películas con ratings)
+	CatalogDensity float64   `json:"catalogDensity,omitempty"` // ratings / (usuarios × películas del catálogo)
+	Top20Share     float64   `json:"top20Share"`               // fracción de ratings del 20% de películas más valoradas
+	HeadFor80      float64   `json:"headFor80"`                // fracción de películas que concentran el 80% de los ratings
+	FirstRating    string    `json:"firstRating,omitempty"`    // fecha del rating más antiguo
+	LastRating     string    `json:"lastRating,omitempty"`     // fecha del rating más reciente
+}
+
+// MovieProfile son las métricas calculadas sobre las películas escritas
+type MovieProfile struct {
+	Movies         int      `json:"movies"`
+	Genres         []Bucket `json:"genres"`
+	Decades        []Bucket `json:"decades"`
+	MinYear        int      `json:"minYear,omitempty"`
+	MaxYear        int      `json:"maxYear,omitempty"`
+	WithoutYear    int      `json:"withoutYear"`
+	LinksCoverage  float64  `json:"linksCoverage"`
+	GenomeCoverage float64  `json:"genomeCoverage"`
+	TMDBCoverage   float64  `json:"tmdbCoverage"`
+}
+
+// DatasetProfile es el perfil completo que se guarda en profile.json
+type DatasetProfile struct {
+	Ratings *RatingProfile `json:"ratings,omitempty"`
+	Movies  *MovieProfile  `json:"movies,omitempty"`
+}
+
+// Profiler calcula el perfil del dataset en la misma pasada que ProcessRatings (RatingSink)
+// y ProcessMovies (MovieSink), sin releer las entradas
+type Profiler struct {
+	ratingCount map[string]int // valor del rating -> cantidad
+	perUser     map[int]int
+	perMovie    map[int]int
+	sum         float64
+	minTs       int64
+	maxTs       int64
+
+	movies      int
+	genres      map[string]int
+	decades     map[int]int
+	minYear     int
+	maxYear     int
+	withoutYear int
+	withLinks   int
+	withGenome  int
+	withTMDB    int
+
+	profile DatasetProfile
+}
+
+// NewProfiler crea un perfilador vacío
+func NewProfiler() *Profiler {
+	return &Profiler{
+		ratingCount: make(map[string]int),
+		perUser:     make(map[int]int),
+		perMovie:    make(map[int]int),
+		genres:      make(map[string]int),
+		decades:     make(map[int]int),
+	}
+}
+
+// Add acumula un rating escrito (RatingSink)
+func (p *Profiler) Add(doc models.RatingDoc) error {
+	p.ratingCount[strconv.FormatFloat(doc.Rating, 'f', -1, 64)]++
+	p.perUser[doc.UserID]++
+	p.perMovie[doc.MovieID]++
+	p.sum += doc.Rating
+	if doc.Timestamp > 0 {
+		if p.minTs == 0 || doc.Timestamp < p.minTs {
+			p.minTs = doc.Timestamp
+		}
+		if doc.Timestamp > p.maxTs {
+			p.maxTs = doc.Timestamp
+		}
+	}
+	return nil
+}
+
+// Close calcula las métricas de ratings (RatingSink)
+func (p *Profiler) Close() error {
+	total := 0
+	for _, n := range p.ratingCount {
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+	rp := &RatingProfile{
+		Ratings:  total,
+		Users:    len(p.perUser),
+		Movies:   len(p.perMovie),
+		Mean:     roundTo(p.sum/float64(total), 4),
+		PerUser:  quantiles(p.perUser),
+		PerMovie: quantiles(p.perMovie),
+		Density:  roundTo(float64(total)/(float64(len(p.perUser))*float64(len(p.perMovie))), 6),
+	}
+	values := make([]string, 0, len(p.ratingCount))
+	for v := range p.ratingCount {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		a, _ := strconv.ParseFloat(values[i], 64)
+		b, _ := strconv.ParseFloat(values[j], 64)
+		return a < b
+	})
+	for _, v := range values {
+		rp.Histogram = append(rp.Histogram, Bucket{Value: v, Count: p.ratingCount[v]})
+	}
+
+	// Cola larga: películas ordenadas por cantidad de ratings, de mayor a menor
+	counts := make([]int, 0, len(p.perMovie))
+	for _, n := range p.perMovie {
+		counts = append(counts, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	top := int(math.Ceil(float64(len(counts)) * 0.2))
+	acc, head := 0, 0
+	for i, n := range counts {
+		acc += n
+		if i+1 == top {
+			rp.Top20Share = roundTo(float64(acc)/float64(total), 4)
+		}
+		if head == 0 && float64(acc) >= 0.8*float64(total) {
+			head = i + 1
+		}
+	}
+	rp.HeadFor80 = roundTo(float64(head)/float64(len(counts)), 4)
+
+	if p.minTs > 0 {
+		rp.FirstRating = time.Unix(p.minTs, 0).UTC().Format("2006-01-02")
+		rp.LastRating = time.Unix(p.maxTs, 0).UTC().Format("2006-01-02")
+	}
+	p.profile.Ratings = rp
+	p.catalogDensity()
+	return nil
+}
+
+// AddMovie acumula una película escrita (MovieSink)
+func (p *Profiler) AddMovie(doc models.MovieDoc) error {
+	p.movies++
+	for _, g := range doc.Genres {
+		p.genres[g]++
+	}
+	if doc.Year != nil {
+		y := *doc.Year
+		p.decades[y/10*10]++
+		if p.minYear == 0 || y < p.minYear {
+			p.minYear = y
+		}
+		if y > p.maxYear {
+			p.maxYear = y
+		}
+	} else {
+		p.withoutYear++
+	}
+	if doc.Links != nil {
+		p.withLinks++
+	}
+	if len(doc.GenomeTags) > 0 {
+		p.withGenome++
+	}
+	if doc.ExternalData != nil && doc.ExternalData.TMDBFetched {
+		p.withTMDB++
+	}
+	return nil
+}
+
+// FinishMovies calcula las métricas de películas (llamar después de ProcessMovies)
+func (p *Profiler) FinishMovies() {
+	if p.movies == 0 {
+		return
+	}
+	mp := &MovieProfile{
+		Movies:         p.movies,
+		Genres:         ranking(p.genres),
+		MinYear:        p.minYear,
+		MaxYear:        p.maxYear,
+		WithoutYear:    p.withoutYear,
+		LinksCoverage:  roundTo(float64(p.withLinks)/float64(p.movies), 4),
+		GenomeCoverage: roundTo(float64(p.withGenome)/float64(p.movies), 4),
+		TMDBCoverage:   roundTo(float64(p.withTMDB)/float64(p.movies), 4),
+	}
+	decades := make([]int, 0, len(p.decades))
+	for d := range p.decades {
+		decades = append(decades, d)
+	}
+	sort.Ints(decades)
+	for _, d := range decades {
+		mp.Decades = append(mp.Decades, Bucket{Value: fmt.Sprintf("%ds", d), Count: p.decades[d]})
+	}
+	p.profile.Movies = mp
+	p.catalogDensity()
+}
+
+// catalogDensity calcula la densidad respecto del catálogo completo cuando hay ratings y películas
+func (p *Profiler) catalogDensity() {
+	if p.profile.Ratings != nil && p.profile.Movies != nil && p.profile.Ratings.Users > 0 {
+		r := p.profile.Ratings
+		r.CatalogDensity = roundTo(float64(r.Ratings)/(float64(r.Users)*float64(p.profile.Movies.Movies)), 6)
+	}
+}
+
+// Profile devuelve el perfil calculado
+func (p *Profiler) Profile() DatasetProfile {
+	return p.profile
+}
+
+// WriteJSON guarda el perfil en formato JSON
+func (p *Profiler) WriteJSON(path string) error {
+	b, err := json.MarshalIndent(p.profile, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Lines devuelve el resumen legible (consola y report.txt)
+func (p *Profiler) Lines() []string {
+	var lines []string
+	if r := p.profile.Ratings; r != nil {
+		hist := make([]string, len(r.Histogram))
+		for i, b := range r.Histogram {
+			hist[i] = fmt.Sprintf("%s: %.1f%%", b.Value, 100*float64(b.Count)/float64(r.Ratings))
+		}
+		lines = append(lines,
+			fmt.Sprintf("  • Ratings: %d de %d usuarios sobre %d películas (media %.2f)", r.Ratings, r.Users, r.Movies, r.Mean),
+			fmt.Sprintf("  • Distribución: %s", strings.Join(hist, ", ")),
+			fmt.Sprintf("  • Ratings por usuario: %s", r.PerUser),
+			fmt.Sprintf("  • Ratings por película: %s", r.PerMovie),
+			fmt.Sprintf("  • Densidad: %.4f%%", 100*r.Density),
+			fmt.Sprintf("  • Cola larga: el 20%% de las películas concentra el %.1f%% de los ratings; el 80%% se alcanza con el %.1f%% de las películas",
+				100*r.Top20Share, 100*r.HeadFor80),
+		)
+		if r.CatalogDensity > 0 {
+			lines = append(lines, fmt.Sprintf("  • Densidad sobre el catálogo: %.4f%%", 100*r.CatalogDensity))
+		}
+		if r.FirstRating != "" {
+			lines = append(lines, fmt.Sprintf("  • Período: %s a %s", r.FirstRating, r.LastRating))
+		}
+	}
+	if m := p.profile.Movies; m != nil {
+		genres := m.Genres
+		if len(genres) > 10 {
+			genres = genres[:10]
+		}
+		top := make([]string, len(genres))
+		for i, b := range genres {
+			top[i] = fmt.Sprintf("%s (%d)", b.Value, b.Count)
+		}
+		decades := make([]string, len(m.Decades))
+		for i, b := range m.Decades {
+			decades[i] = fmt.Sprintf("%s: %d", b.Value, b.Count)
+		}
+		lines = append(lines,
+			fmt.Sprintf("  • Películas: %d (%d géneros distintos)", m.Movies, len(m.Genres)),
+			fmt.Sprintf("  • Géneros más frecuentes: %s", strings.Join(top, ", ")),
+		)
+		if m.MaxYear > 0 {
+			lines = append(lines, fmt.Sprintf("  • Años %d-%d (%d sin año): %s", m.MinYear, m.MaxYear, m.WithoutYear, strings.Join(decades, ", ")))
+		}
+		lines = append(lines, fmt.Sprintf("  • Cobertura: links %.1f%%, genome %.1f%%, TMDB %.1f%%",
+			100*m.LinksCoverage, 100*m.GenomeCoverage, 100*m.TMDBCoverage))
+	}
+	return lines
+}
+
+// String formatea los cuantiles en una línea
+func (q Quantiles) String() string {
+	return fmt.Sprintf("min %d, p25 %d, mediana %d, p75 %d, p90 %d, p99 %d, máx %d (media %.1f)",
+		q.Min, q.P25, q.P50, q.P75, q.P90, q.P99, q.Max, q.Mean)
+}
+
+// quantiles calcula los cuantiles (rango más cercano) de los conteos
+func quantiles(counts map[int]int) Quantiles {
+	values := make([]int, 0, len(counts))
+	sum := 0
+	for _, n := range counts {
+		values = append(values, n)
+		sum += n
+	}
+	if len(values) == 0 {
+		return Quantiles{}
+	}
+	sort.Ints(values)
+	at := func(q float64) int {
+		i := int(math.Ceil(q*float64(len(values)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return values[i]
+	}
+	return Quantiles{
+		Min:  values[0],
+		P25:  at(0.25),
+		P50:  at(0.50),
+		P75:  at(0.75),
+		P90:  at(0.90),
+		P99:  at(0.99),
+		Max:  values[len(values)-1],
+		Mean: roundTo(float64(sum)/float64(len(values)), 4),
+	}
+}
+
+// ranking ordena las frecuencias de mayor a menor (empates por nombre)
+func ranking(freq map[string]int) []Bucket {
+	out := make([]Bucket, 0, len(freq))
+	for v, n := range freq {
+		out = append(out, Bucket{Value: v, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
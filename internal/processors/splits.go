@@ -0,0 +1,255 @@
+package processors
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"pc4_etl/internal/mappers"
+	"pc4_etl/internal/models"
+)
+
+// Estrategias de partición soportadas por SplitRatings
+const (
+	SplitRandom      = "random"
+	SplitTemporal    = "temporal"
+	SplitLeaveOneOut = "leave-one-out"
+)
+
+// SplitOptions configura la partición train/validation/test
+type SplitOptions struct {
+	Strategy   string  // random | temporal | leave-one-out
+	Seed       int64   // semilla para la estrategia random
+	MinRatings int     // usuarios con menos ratings se descartan
+	ValRatio   float64 // random: fracción de ratings por usuario para validation
+	TestRatio  float64 // random: fracción de ratings por usuario para test
+	ValN       int     // temporal: últimos N ratings (antes de test) para validation; leave-one-out: 0 = sin validation
+	TestN      int     // temporal: últimos N ratings para test
+}
+
+// SplitResult resume la partición generada
+type SplitResult struct {
+	Strategy     string `json:"strategy"`
+	Seed         int64  `json:"seed"`
+	MinRatings   int    `json:"minRatings"`
+	Users        int    `json:"users"`
+	DroppedUsers int    `json:"droppedUsers"`
+	Unmapped     int    `json:"unmapped,omitempty"` // ratings omitidos por userId/movieId sin uIdx/iIdx
+	Train        int    `json:"train"`
+	Validation   int    `json:"validation"`
+	Test         int    `json:"test"`
+}
+
+// splitWriter escribe un split en NDJSON y CSV en paralelo
+type splitWriter struct {
+	files []*os.File
+	nd    *bufio.Writer
+	cw    *csv.Writer
+}
+
+func newSplitWriter(outDir, name string) (*splitWriter, error) {
+	ndFile, err := os.Create(filepath.Join(outDir, name+".ndjson"))
+	if err != nil {
+		return nil, err
+	}
+	csvFile, err := os.Create(filepath.Join(outDir, name+".csv"))
+	if err != nil {
+		ndFile.Close()
+		return nil, err
+	}
+	sw := &splitWriter{
+		files: []*os.File{ndFile, csvFile},
+		nd:    bufio.NewWriter(ndFile),
+		cw:    csv.NewWriter(bufio.NewWriter(csvFile)),
+	}
+	if err := sw.cw.Write([]string{"userId", "uIdx", "movieId", "iIdx", "rating", "timestamp"}); err != nil {
+		sw.close()
+		return nil, err
+	}
+	return sw, nil
+}
+
+func (sw *splitWriter) write(r models.SplitRating) error {
+	b, _ := json.Marshal(r)
+	sw.nd.Write(b)
+	sw.nd.WriteByte('\n')
+	return sw.cw.Write([]string{
+		strconv.Itoa(r.UserID),
+		strconv.Itoa(r.UIdx),
+		strconv.Itoa(r.MovieID),
+		strconv.Itoa(r.IIdx),
+		strconv.FormatFloat(r.Rating, 'f', -1, 64),
+		strconv.FormatInt(r.Timestamp, 10),
+	})
+}
+
+func (sw *splitWriter) close() error {
+	var firstErr error
+	if err := sw.nd.Flush(); err != nil {
+		firstErr = err
+	}
+	sw.cw.Flush()
+	if err := sw.cw.Error(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	for _, f := range sw.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SplitRatings particiona los ratings por usuario en train/validation/test y escribe
+// train.{ndjson,csv}, validation.{ndjson,csv}, test.{ndjson,csv} y split.json en outDir. No asigna
+// índices: los ratings de ids sin uIdx/iIdx en los mapeadores se omiten (ver AssignItemIndices)
+func SplitRatings(ratings []models.RatingDoc, outDir string, opts SplitOptions, userMapper, itemMapper *mappers.IDMapper) (SplitResult, error) {
+	result := SplitResult{Strategy: opts.Strategy, Seed: opts.Seed, MinRatings: opts.MinRatings}
+
+	switch opts.Strategy {
+	case SplitRandom:
+		if opts.ValRatio < 0 || opts.TestRatio < 0 || opts.ValRatio+opts.TestRatio >= 1 {
+			return result, fmt.Errorf("proporciones inválidas: val=%.2f test=%.2f", opts.ValRatio, opts.TestRatio)
+		}
+	case SplitTemporal:
+		if opts.ValN < 0 || opts.TestN <= 0 {
+			return result, fmt.Errorf("valores inválidos para split temporal: val-n=%d test-n=%d", opts.ValN, opts.TestN)
+		}
+	case SplitLeaveOneOut:
+	default:
+		return result, fmt.Errorf("estrategia de split desconocida: %s", opts.Strategy)
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return result, err
+	}
+
+	// Agrupar por usuario
+	byUser := make(map[int][]models.RatingDoc)
+	for _, r := range ratings {
+		if userMapper.Get(r.UserID) < userMapper.Base() || itemMapper.Get(r.MovieID) < itemMapper.Base() {
+			result.Unmapped++
+			continue
+		}
+		byUser[r.UserID] = append(byUser[r.UserID], r)
+	}
+	userIds := make([]int, 0, len(byUser))
+	for uid := range byUser {
+		userIds = append(userIds, uid)
+	}
+	sort.Ints(userIds)
+
+	writers := make([]*splitWriter, 3)
+	for i, name := range []string{"train", "validation", "test"} {
+		sw, err := newSplitWriter(outDir, name)
+		if err != nil {
+			for _, prev := range writers[:i] {
+				prev.close()
+			}
+			return result, err
+		}
+		writers[i] = sw
+	}
+	train, validation, test := writers[0], writers[1], writers[2]
+
+	rng := mathrand.New(mathrand.NewSource(opts.Seed))
+
+	for _, uid := range userIds {
+		userRatings := byUser[uid]
+		if len(userRatings) < opts.MinRatings {
+			result.DroppedUsers++
+			continue
+		}
+		result.Users++
+
+		// Orden estable: por timestamp y luego movieId para que la salida sea reproducible
+		sort.SliceStable(userRatings, func(i, j int) bool {
+			if userRatings[i].Timestamp != userRatings[j].Timestamp {
+				return userRatings[i].Timestamp < userRatings[j].Timestamp
+			}
+			return userRatings[i].MovieID < userRatings[j].MovieID
+		})
+
+		// nTest/nVal: cuántos ratings van a test y validation (tomados del final)
+		nTest, nVal := 0, 0
+		switch opts.Strategy {
+		case SplitRandom:
+			rng.Shuffle(len(userRatings), func(i, j int) {
+				userRatings[i], userRatings[j] = userRatings[j], userRatings[i]
+			})
+			nTest = int(float64(len(userRatings)) * opts.TestRatio)
+			nVal = int(float64(len(userRatings)) * opts.ValRatio)
+		case SplitTemporal:
+			nTest, nVal = opts.TestN, opts.ValN
+		case SplitLeaveOneOut:
+			// Último rating a test y, si se pidió validation, el penúltimo a validation
+			nTest = 1
+			if opts.ValN > 0 {
+				nVal = 1
+			}
+		}
+		// Garantizar al menos un rating en train por usuario
+		if nTest > len(userRatings)-1 {
+			nTest = len(userRatings) - 1
+		}
+		if nVal > len(userRatings)-1-nTest {
+			nVal = len(userRatings) - 1 - nTest
+		}
+		if nTest < 0 {
+			nTest = 0
+		}
+		if nVal < 0 {
+			nVal = 0
+		}
+
+		uIdx := userMapper.Get(uid)
+		testStart := len(userRatings) - nTest
+		valStart := testStart - nVal
+		for i, r := range userRatings {
+			sr := models.SplitRating{
+				UserID:    r.UserID,
+				UIdx:      uIdx,
+				MovieID:   r.MovieID,
+				IIdx:      itemMapper.Get(r.MovieID),
+				Rating:    r.Rating,
+				Timestamp: r.Timestamp,
+			}
+			var err error
+			switch {
+			case i >= testStart:
+				err = test.write(sr)
+				result.Test++
+			case i >= valStart:
+				err = validation.write(sr)
+				result.Validation++
+			default:
+				err = train.write(sr)
+				result.Train++
+			}
+			if err != nil {
+				for _, sw := range writers {
+					sw.close()
+				}
+				return result, err
+			}
+		}
+	}
+
+	for _, sw := range writers {
+		if err := sw.close(); err != nil {
+			return result, err
+		}
+	}
+
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return result, err
+	}
+	return result, os.WriteFile(filepath.Join(outDir, "split.json"), append(b, '\n'), 0o644)
+}
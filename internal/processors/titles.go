@@ -0,0 +1,128 @@
+package processors
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// trailingArticles son los artículos que MovieLens pospone al final del título ("Matrix, The"),
+// en inglés, español, francés, italiano, alemán, portugués, neerlandés y escandinavo
+var trailingArticles = map[string]bool{
+	"The": true, "A": true, "An": true,
+	"El": true, "La": true, "Los": true, "Las": true, "Un": true, "Una": true,
+	"Le": true, "Les": true, "L'": true, "Une": true,
+	"Il": true, "Lo": true, "I": true, "Gli": true, "Uno": true,
+	"Der": true, "Die": true, "Das": true, "Ein": true, "Eine": true,
+	"O": true, "Os": true, "As": true,
+	"De": true, "Het": true, "Een": true,
+	"Den": true, "Det": true, "En": true, "Ett": true,
+}
+
+// akaPrefix reconoce los títulos alternativos explícitos ("a.k.a. Se7en")
+var akaPrefix = regexp.MustCompile(`(?i)^a\.?k\.?a\.?\s+`)
+
+// ParsedTitle es el resultado de interpretar un título de MovieLens
+type ParsedTitle struct {
+	Title           string
+	AlternateTitles []string
+	Year            *int
+	EndYear         *int
+	Ongoing         bool   // rango abierto, ej: "(2007-)"
+	Article         string // artículo pospuesto que se antepuso ("The" en "Matrix, The"), "" si no había
+	SortTitle       string
+}
+
+// parseTitle interpreta títulos como "City of Lost Children, The (Cité des enfants perdus, La) (1995)":
+// extrae el año o rango final (yearRe: año inicial, guion opcional y año final), separa los títulos
+// alternativos entre paréntesis (incluyendo "a.k.a.") y antepone los artículos pospuestos
+func parseTitle(raw string, yearRe *regexp.Regexp) ParsedTitle {
+	var p ParsedTitle
+	title := strings.TrimSpace(raw)
+
+	if m := yearRe.FindStringSubmatchIndex(title); m != nil {
+		groups := yearRe.FindStringSubmatch(title)
+		if y, err := strconv.Atoi(groups[1]); err == nil {
+			p.Year = &y
+			if len(groups) > 3 && groups[3] != "" {
+				if end, err := strconv.Atoi(groups[3]); err == nil {
+					p.EndYear = &end
+				}
+			} else if len(groups) > 2 && groups[2] != "" {
+				p.Ongoing = true
+			}
+			if m[0] > 0 {
+				title = strings.TrimSpace(title[:m[0]])
+			}
+		}
+	}
+
+	// Grupos entre paréntesis al final: títulos alternativos (del último al primero)
+	var alternates []string
+	for strings.HasSuffix(title, ")") {
+		open := matchingParen(title)
+		if open <= 0 {
+			break
+		}
+		alt := strings.TrimSpace(akaPrefix.ReplaceAllString(strings.TrimSpace(title[open+1:len(title)-1]), ""))
+		title = strings.TrimSpace(title[:open])
+		if alt != "" {
+			alt, _ = restoreArticle(alt)
+			alternates = append([]string{alt}, alternates...)
+		}
+	}
+
+	p.Title, p.Article = restoreArticle(title)
+	p.AlternateTitles = alternates
+	p.SortTitle = sortKey(p.Title, p.Article)
+	return p
+}
+
+// matchingParen devuelve la posición del "(" que abre el paréntesis final (-1 si no está balanceado)
+func matchingParen(s string) int {
+	depth := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// restoreArticle convierte "Matrix, The" en "The Matrix" y "Avventura, L'" en "L'Avventura";
+// devuelve también el artículo antepuesto ("" si el título no tenía uno pospuesto)
+func restoreArticle(title string) (string, string) {
+	i := strings.LastIndex(title, ", ")
+	if i <= 0 {
+		return title, ""
+	}
+	article := strings.TrimSpace(title[i+2:])
+	if !trailingArticles[article] {
+		return title, ""
+	}
+	if strings.HasSuffix(article, "'") {
+		return article + title[:i], article
+	}
+	return article + " " + title[:i], article
+}
+
+// sortKey devuelve la clave de ordenamiento: el título en minúsculas sin el artículo que
+// restoreArticle antepuso. Los títulos que ya empiezan con una palabra como "Die" o "I" ("Die Hard",
+// "I Am Legend") no se tocan: solo el dato de MovieLens indica si es un artículo
+func sortKey(title, article string) string {
+	if article != "" {
+		if !strings.HasSuffix(article, "'") {
+			article += " "
+		}
+		if rest := strings.TrimPrefix(title, article); rest != "" {
+			title = rest
+		}
+	}
+	return strings.ToLower(title)
+}
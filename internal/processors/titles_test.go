@@ -0,0 +1,57 @@
+package processors
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// Mismo patrón que yearRe en main.go
+var testYearRe = regexp.MustCompile(`\((\d{4})\s*([-–]\s*(\d{4})?)?\)\s*$`)
+
+func intp(v int) *int { return &v }
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want ParsedTitle
+	}{
+		{"Toy Story (1995)", ParsedTitle{Title: "Toy Story", Year: intp(1995), SortTitle: "toy story"}},
+		{"Matrix, The (1999)", ParsedTitle{Title: "The Matrix", Year: intp(1999), Article: "The", SortTitle: "matrix"}},
+		{"Avventura, L' (1960)", ParsedTitle{Title: "L'Avventura", Year: intp(1960), Article: "L'", SortTitle: "avventura"}},
+		{"City of Lost Children, The (Cité des enfants perdus, La) (1995)", ParsedTitle{
+			Title: "The City of Lost Children", AlternateTitles: []string{"La Cité des enfants perdus"},
+			Year: intp(1995), Article: "The", SortTitle: "city of lost children"}},
+		{"Seven (a.k.a. Se7en) (1995)", ParsedTitle{Title: "Seven", AlternateTitles: []string{"Se7en"}, Year: intp(1995), SortTitle: "seven"}},
+		{"Sherlock (2010-2017)", ParsedTitle{Title: "Sherlock", Year: intp(2010), EndYear: intp(2017), SortTitle: "sherlock"}},
+		{"Doctor Who (2005-)", ParsedTitle{Title: "Doctor Who", Year: intp(2005), Ongoing: true, SortTitle: "doctor who"}},
+		{"Untitled", ParsedTitle{Title: "Untitled", SortTitle: "untitled"}},
+
+		// Títulos que empiezan con una palabra que en otro idioma es artículo: no se recortan
+		{"Die Hard (1988)", ParsedTitle{Title: "Die Hard", Year: intp(1988), SortTitle: "die hard"}},
+		{"I Am Legend (2007)", ParsedTitle{Title: "I Am Legend", Year: intp(2007), SortTitle: "i am legend"}},
+		{"Den of Thieves (2018)", ParsedTitle{Title: "Den of Thieves", Year: intp(2018), SortTitle: "den of thieves"}},
+		{"As Good as It Gets (1997)", ParsedTitle{Title: "As Good as It Gets", Year: intp(1997), SortTitle: "as good as it gets"}},
+		{"O Brother, Where Art Thou? (2000)", ParsedTitle{Title: "O Brother, Where Art Thou?", Year: intp(2000), SortTitle: "o brother, where art thou?"}},
+	}
+	for _, tt := range tests {
+		got := parseTitle(tt.raw, testYearRe)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTitle(%q)\n got  %+v\n want %+v", tt.raw, describe(got), describe(tt.want))
+		}
+	}
+}
+
+// describe muestra los punteros de año por valor en los mensajes de error
+func describe(p ParsedTitle) map[string]interface{} {
+	year := func(v *int) interface{} {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+	return map[string]interface{}{
+		"Title": p.Title, "AlternateTitles": p.AlternateTitles, "Year": year(p.Year), "EndYear": year(p.EndYear),
+		"Ongoing": p.Ongoing, "Article": p.Article, "SortTitle": p.SortTitle,
+	}
+}
@@ -0,0 +1,136 @@
+package processors
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"pc4_etl/internal/mappers"
+	"pc4_etl/internal/models"
+)
+
+// UserBucketWriter agrupa los ratings de cada usuario en documentos de tamaño fijo (user_ratings.ndjson).
+// ratings.csv viene ordenado por userId, por lo que el bucket abierto se cierra al cambiar de usuario;
+// si un usuario reaparece más adelante se continúa con el siguiente número de bucket. Los uIdx/iIdx
+// se toman de los mapeos sin crear índices nuevos (se omiten los de ids sin mapeo).
+type UserBucketWriter struct {
+	f          *os.File
+	w          *bufio.Writer
+	bucketSize int
+	userMapper *mappers.IDMapper
+	itemMapper *mappers.IDMapper
+	current    *models.UserRatingBucket
+	nextBucket map[int]int
+	written    int
+}
+
+// NewUserBucketWriter crea el archivo de salida para los buckets de ratings por usuario
+func NewUserBucketWriter(outPath string, bucketSize int, userMapper, itemMapper *mappers.IDMapper) (*UserBucketWriter, error) {
+	if bucketSize <= 0 {
+		return nil, fmt.Errorf("tamaño de bucket inválido: %d", bucketSize)
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return nil, err
+	}
+	return &UserBucketWriter{
+		f:          f,
+		w:          bufio.NewWriter(f),
+		bucketSize: bucketSize,
+		userMapper: userMapper,
+		itemMapper: itemMapper,
+		nextBucket: make(map[int]int),
+	}, nil
+}
+
+// Add agrega un rating al bucket abierto del usuario, escribiéndolo cuando se llena
+func (b *UserBucketWriter) Add(doc models.RatingDoc) error {
+	if b.current != nil && b.current.UserID != doc.UserID {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+	if b.current == nil {
+		b.open(doc.UserID)
+	}
+
+	entry := models.BucketRating{
+		MovieID:   doc.MovieID,
+		Rating:    doc.Rating,
+		Timestamp: doc.Timestamp,
+	}
+	if b.itemMapper != nil {
+		if iIdx := b.itemMapper.Get(doc.MovieID); iIdx >= 0 {
+			entry.IIdx = &iIdx
+		}
+	}
+
+	bucket := b.current
+	if bucket.Count == 0 || doc.Timestamp < bucket.MinTimestamp {
+		bucket.MinTimestamp = doc.Timestamp
+	}
+	if bucket.Count == 0 || doc.Timestamp > bucket.MaxTimestamp {
+		bucket.MaxTimestamp = doc.Timestamp
+	}
+	bucket.Ratings = append(bucket.Ratings, entry)
+	bucket.Count++
+
+	if bucket.Count >= b.bucketSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// Close escribe el último bucket abierto y cierra el archivo
+func (b *UserBucketWriter) Close() error {
+	if err := b.flush(); err != nil {
+		b.f.Close()
+		return err
+	}
+	if err := b.w.Flush(); err != nil {
+		b.f.Close()
+		return err
+	}
+	return b.f.Close()
+}
+
+// Count devuelve el número de buckets escritos
+func (b *UserBucketWriter) Count() int {
+	return b.written
+}
+
+// open inicia un nuevo bucket para el usuario
+func (b *UserBucketWriter) open(uid int) {
+	seq := b.nextBucket[uid]
+	b.nextBucket[uid] = seq + 1
+
+	b.current = &models.UserRatingBucket{
+		ID:      fmt.Sprintf("%d_%d", uid, seq),
+		UserID:  uid,
+		Bucket:  seq,
+		Ratings: make([]models.BucketRating, 0, b.bucketSize),
+	}
+	if b.userMapper != nil {
+		if uIdx := b.userMapper.Get(uid); uIdx >= 0 {
+			b.current.UIdx = &uIdx
+		}
+	}
+}
+
+// flush escribe el bucket abierto (si tiene ratings) como una línea NDJSON
+func (b *UserBucketWriter) flush() error {
+	if b.current == nil || b.current.Count == 0 {
+		b.current = nil
+		return nil
+	}
+	data, err := json.Marshal(b.current)
+	if err != nil {
+		return err
+	}
+	b.w.Write(data)
+	b.w.WriteByte('\n')
+	b.written++
+	b.current = nil
+	return nil
+}
@@ -0,0 +1,97 @@
+package sources
+
+import (
+	"strconv"
+	"strings"
+
+	"pc4_etl/internal/inputs"
+)
+
+// csvRatings lee ratings de un CSV con cabecera y columnas configurables
+type csvRatings struct {
+	r                         *inputs.CSVFile
+	user, item, rating, tsCol int
+}
+
+func newCSVRatings(cfg Config) (*csvRatings, error) {
+	if cfg.Delimiter != 0 {
+		inputs.SetDelimiter("source-ratings", cfg.Delimiter)
+	}
+	r, err := inputs.OpenCSV(cfg.RatingsPath, "source-ratings", cfg.UserField, cfg.ItemField, cfg.RatingField)
+	if err != nil {
+		return nil, err
+	}
+	return &csvRatings{
+		r:      r,
+		user:   r.Index(cfg.UserField),
+		item:   r.Index(cfg.ItemField),
+		rating: r.Index(cfg.RatingField),
+		tsCol:  r.Index(cfg.TimeField),
+	}, nil
+}
+
+func (s *csvRatings) Next() (Rating, error) {
+	rec, err := s.r.Read()
+	if err != nil {
+		return Rating{}, err
+	}
+	rating, err := strconv.ParseFloat(strings.TrimSpace(inputs.Field(rec, s.rating)), 64)
+	if err != nil {
+		return Rating{}, err
+	}
+	return Rating{
+		UserKey:   strings.TrimSpace(inputs.Field(rec, s.user)),
+		ItemKey:   strings.TrimSpace(inputs.Field(rec, s.item)),
+		Rating:    rating,
+		Timestamp: parseTimestamp(inputs.Field(rec, s.tsCol)),
+	}, nil
+}
+
+func (s *csvRatings) Close() error {
+	return s.r.Close()
+}
+
+// csvItems lee items de un CSV con cabecera (título y géneros opcionales)
+type csvItems struct {
+	r                  *inputs.CSVFile
+	key, title, genres int
+	genresSep          string
+}
+
+func newCSVItems(cfg Config) (*csvItems, error) {
+	if cfg.Delimiter != 0 {
+		inputs.SetDelimiter("source-items", cfg.Delimiter)
+	}
+	r, err := inputs.OpenCSV(cfg.ItemsPath, "source-items", cfg.ItemField)
+	if err != nil {
+		return nil, err
+	}
+	return &csvItems{
+		r:         r,
+		key:       r.Index(cfg.ItemField),
+		title:     r.Index(cfg.TitleField),
+		genres:    r.Index(cfg.GenresField),
+		genresSep: cfg.GenresSep,
+	}, nil
+}
+
+func (s *csvItems) Next() (Item, error) {
+	rec, err := s.r.Read()
+	if err != nil {
+		return Item{}, err
+	}
+	it := Item{
+		Key:   strings.TrimSpace(inputs.Field(rec, s.key)),
+		Title: strings.TrimSpace(inputs.Field(rec, s.title)),
+	}
+	for _, g := range strings.Split(inputs.Field(rec, s.genres), s.genresSep) {
+		if g = strings.TrimSpace(g); g != "" {
+			it.Genres = append(it.Genres, g)
+		}
+	}
+	return it, nil
+}
+
+func (s *csvItems) Close() error {
+	return s.r.Close()
+}
@@ -0,0 +1,155 @@
+package sources
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"pc4_etl/internal/inputs"
+)
+
+// jsonlReader decodifica un objeto JSON por línea (las líneas vacías se ignoran)
+type jsonlReader struct {
+	rc      io.ReadCloser
+	scanner *bufio.Scanner
+	done    bool
+}
+
+func openJSONL(path string) (*jsonlReader, error) {
+	rc, err := inputs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &jsonlReader{rc: rc, scanner: scanner}, nil
+}
+
+func (j *jsonlReader) next() (map[string]interface{}, error) {
+	if j.done {
+		return nil, io.EOF
+	}
+	for j.scanner.Scan() {
+		line := strings.TrimSpace(j.scanner.Text())
+		if line == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+	// El error de lectura (si lo hay) se informa una sola vez
+	j.done = true
+	if err := j.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (j *jsonlReader) Close() error {
+	return j.rc.Close()
+}
+
+// jsonString convierte un valor JSON escalar a string (números sin notación exponencial)
+func jsonString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return strings.TrimSpace(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// jsonStrings aplana strings y arreglos (anidados) de strings, ej: categories de Amazon
+func jsonStrings(v interface{}) []string {
+	switch x := v.(type) {
+	case []interface{}:
+		var out []string
+		for _, e := range x {
+			out = append(out, jsonStrings(e)...)
+		}
+		return out
+	case nil:
+		return nil
+	default:
+		if s := jsonString(x); s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+}
+
+// jsonlRatings lee ratings de un dump JSON-lines de reseñas
+type jsonlRatings struct {
+	*jsonlReader
+	cfg Config
+}
+
+func newJSONLRatings(cfg Config) (*jsonlRatings, error) {
+	j, err := openJSONL(cfg.RatingsPath)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonlRatings{jsonlReader: j, cfg: cfg}, nil
+}
+
+func (s *jsonlRatings) Next() (Rating, error) {
+	obj, err := s.next()
+	if err != nil {
+		return Rating{}, err
+	}
+	rating, err := strconv.ParseFloat(jsonString(obj[s.cfg.RatingField]), 64)
+	if err != nil {
+		return Rating{}, err
+	}
+	return Rating{
+		UserKey:   jsonString(obj[s.cfg.UserField]),
+		ItemKey:   jsonString(obj[s.cfg.ItemField]),
+		Rating:    rating,
+		Timestamp: parseTimestamp(jsonString(obj[s.cfg.TimeField])),
+	}, nil
+}
+
+// jsonlItems lee items de un dump JSON-lines de metadatos
+type jsonlItems struct {
+	*jsonlReader
+	cfg Config
+}
+
+func newJSONLItems(cfg Config) (*jsonlItems, error) {
+	j, err := openJSONL(cfg.ItemsPath)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonlItems{jsonlReader: j, cfg: cfg}, nil
+}
+
+func (s *jsonlItems) Next() (Item, error) {
+	obj, err := s.next()
+	if err != nil {
+		return Item{}, err
+	}
+	it := Item{
+		Key:   jsonString(obj[s.cfg.ItemField]),
+		Title: jsonString(obj[s.cfg.TitleField]),
+	}
+	seen := make(map[string]bool)
+	for _, g := range jsonStrings(obj[s.cfg.GenresField]) {
+		if !seen[g] {
+			seen[g] = true
+			it.Genres = append(it.Genres, g)
+		}
+	}
+	return it, nil
+}
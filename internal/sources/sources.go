@@ -0,0 +1,303 @@
+package sources
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"pc4_etl/internal/loaders"
+	"pc4_etl/internal/mappers"
+)
+
+// Formatos de datasets externos (IDs string, campos configurables)
+const (
+	FormatGenericCSV = "generic-csv" // CSV con cabecera (ej: Book-Crossing)
+	FormatJSONL      = "jsonl"       // JSON-lines de reseñas (ej: Amazon reviews)
+)
+
+// IsSourceFormat indica si el formato se lee mediante un RatingsSource/ItemsSource
+func IsSourceFormat(format string) bool {
+	return format == FormatGenericCSV || format == FormatJSONL
+}
+
+// Rating es un rating crudo con claves string tal como vienen en el dataset de origen
+type Rating struct {
+	UserKey   string
+	ItemKey   string
+	Rating    float64
+	Timestamp int64
+}
+
+// Item es un item crudo con clave string
+type Item struct {
+	Key    string
+	Title  string
+	Genres []string
+}
+
+// RatingsSource entrega ratings de cualquier dataset; Next devuelve io.EOF al terminar
+type RatingsSource interface {
+	Next() (Rating, error)
+	Close() error
+}
+
+// ItemsSource entrega items de cualquier dataset; Next devuelve io.EOF al terminar
+type ItemsSource interface {
+	Next() (Item, error)
+	Close() error
+}
+
+// Config describe dónde están los datos y cómo se llaman sus campos
+// (nombres de columna en CSV o claves de primer nivel en JSON-lines)
+type Config struct {
+	Format      string
+	RatingsPath string
+	ItemsPath   string // opcional: sin archivo de items se usan las claves de los ratings como título
+	Delimiter   rune   // generic-csv: separador de campos (0 = detectar)
+	GenresSep   string // generic-csv: separador de géneros dentro de la columna
+	UserField   string
+	ItemField   string
+	RatingField string
+	TimeField   string
+	TitleField  string
+	GenresField string
+	KeysDir     string // directorio con item_keys.csv / user_keys.csv (mapeo clave -> id)
+}
+
+// withDefaults completa los campos vacíos con los nombres habituales de cada formato
+func (c Config) withDefaults() Config {
+	defaults := []string{"userId", "movieId", "rating", "timestamp", "title", "genres"}
+	if c.Format == FormatJSONL {
+		// Convenciones de los dumps de Amazon reviews / metadata
+		defaults = []string{"reviewerID", "asin", "overall", "unixReviewTime", "title", "categories"}
+	}
+	fields := []*string{&c.UserField, &c.ItemField, &c.RatingField, &c.TimeField, &c.TitleField, &c.GenresField}
+	for i, f := range fields {
+		if *f == "" {
+			*f = defaults[i]
+		}
+	}
+	if c.GenresSep == "" {
+		c.GenresSep = "|"
+	}
+	return c
+}
+
+// OpenRatings abre el RatingsSource correspondiente al formato
+func OpenRatings(cfg Config) (RatingsSource, error) {
+	cfg = cfg.withDefaults()
+	switch cfg.Format {
+	case FormatGenericCSV:
+		return newCSVRatings(cfg)
+	case FormatJSONL:
+		return newJSONLRatings(cfg)
+	default:
+		return nil, fmt.Errorf("formato de origen desconocido: %s", cfg.Format)
+	}
+}
+
+// OpenItems abre el ItemsSource correspondiente al formato
+func OpenItems(cfg Config) (ItemsSource, error) {
+	cfg = cfg.withDefaults()
+	switch cfg.Format {
+	case FormatGenericCSV:
+		return newCSVItems(cfg)
+	case FormatJSONL:
+		return newJSONLItems(cfg)
+	default:
+		return nil, fmt.Errorf("formato de origen desconocido: %s", cfg.Format)
+	}
+}
+
+// Result resume la materialización de un dataset externo
+type Result struct {
+	Files       *loaders.DatasetFiles
+	ItemKeys    *mappers.KeyMapper
+	UserKeys    *mappers.KeyMapper
+	Ratings     int
+	Items       int
+	Skipped     int // ratings descartados (clave vacía o rating no numérico)
+	ItemsLoaded bool
+}
+
+// Materialize lee las fuentes, traduce las claves string a IDs enteros (item_keys.csv /
+// user_keys.csv en KeysDir) y escribe movies.csv y ratings.csv con el layout de ml-latest
+// en stagingDir, para que los loaders y procesadores existentes funcionen sin cambios
+func Materialize(cfg Config, stagingDir string) (*Result, error) {
+	cfg = cfg.withDefaults()
+	if err := os.MkdirAll(stagingDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	res := &Result{
+		Files: &loaders.DatasetFiles{
+			Format:  cfg.Format,
+			Movies:  filepath.Join(stagingDir, "movies.csv"),
+			Ratings: filepath.Join(stagingDir, "ratings.csv"),
+		},
+		ItemKeys: mappers.NewKeyMapper(loadKeys(filepath.Join(cfg.KeysDir, "item_keys.csv"), "movieId")),
+		UserKeys: mappers.NewKeyMapper(loadKeys(filepath.Join(cfg.KeysDir, "user_keys.csv"), "userId")),
+	}
+
+	// Items primero para que los IDs sigan el orden del catálogo
+	items := make(map[int]Item)
+	var order []int
+	if cfg.ItemsPath != "" {
+		src, err := OpenItems(cfg)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		} else {
+			res.ItemsLoaded = true
+			for {
+				it, err := src.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil || it.Key == "" {
+					continue
+				}
+				id := res.ItemKeys.GetOrCreate(it.Key)
+				if _, seen := items[id]; !seen {
+					order = append(order, id)
+				}
+				items[id] = it
+			}
+			src.Close()
+		}
+	}
+
+	src, err := OpenRatings(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	out, err := os.Create(res.Files.Ratings)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	w := csv.NewWriter(bufio.NewWriter(out))
+	if err := w.Write([]string{"userId", "movieId", "rating", "timestamp"}); err != nil {
+		return nil, err
+	}
+
+	for {
+		r, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || r.UserKey == "" || r.ItemKey == "" {
+			res.Skipped++
+			continue
+		}
+		uid := res.UserKeys.GetOrCreate(r.UserKey)
+		mid := res.ItemKeys.GetOrCreate(r.ItemKey)
+		if _, seen := items[mid]; !seen {
+			// Item sin metadatos: se usa la clave como título
+			items[mid] = Item{Key: r.ItemKey, Title: r.ItemKey}
+			order = append(order, mid)
+		}
+		if err := w.Write([]string{
+			strconv.Itoa(uid),
+			strconv.Itoa(mid),
+			strconv.FormatFloat(r.Rating, 'f', -1, 64),
+			strconv.FormatInt(r.Timestamp, 10),
+		}); err != nil {
+			return nil, err
+		}
+		res.Ratings++
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	if err := writeItems(res.Files.Movies, items, order); err != nil {
+		return nil, err
+	}
+	res.Items = len(order)
+	return res, nil
+}
+
+// SaveKeys guarda item_keys.csv y user_keys.csv en dir
+func (r *Result) SaveKeys(dir string) error {
+	if err := mappers.SaveKeyMap(filepath.Join(dir, "item_keys.csv"), "movieId", r.ItemKeys.GetMapping()); err != nil {
+		return err
+	}
+	return mappers.SaveKeyMap(filepath.Join(dir, "user_keys.csv"), "userId", r.UserKeys.GetMapping())
+}
+
+// loadKeys carga un mapeo de claves existente (vacío si no existe)
+func loadKeys(path, idHeader string) map[string]int {
+	keyMap, err := loaders.LoadKeyMap(path, idHeader)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar %s: %v\n", filepath.Base(path), err)
+		}
+		return make(map[string]int)
+	}
+	return keyMap
+}
+
+// writeItems escribe movies.csv (movieId,title,genres) en el orden de aparición
+func writeItems(path string, items map[int]Item, order []int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(bufio.NewWriter(f))
+	if err := w.Write([]string{"movieId", "title", "genres"}); err != nil {
+		return err
+	}
+	for _, id := range order {
+		it := items[id]
+		title := strings.TrimSpace(it.Title)
+		if title == "" {
+			title = it.Key
+		}
+		genres := "(no genres listed)"
+		if len(it.Genres) > 0 {
+			genres = strings.Join(it.Genres, "|")
+		}
+		if err := w.Write([]string{strconv.Itoa(id), title, genres}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// timeLayouts son los formatos de fecha aceptados cuando el timestamp no es un entero unix
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02", "01 2, 2006"}
+
+// parseTimestamp interpreta segundos unix (entero o decimal) o una fecha en timeLayouts
+func parseTimestamp(s string) int64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ts
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return int64(f)
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,322 @@
+package taxonomy
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"pc4_etl/internal/inputs"
+	"pc4_etl/internal/models"
+)
+
+// Formatos de salida de los géneros en movies.ndjson
+const (
+	FormatRaw       = "raw"       // tal como vienen en movies.csv
+	FormatCanonical = "canonical" // ids canónicos ("sci-fi", "children")
+	FormatObjects   = "objects"   // objetos {id, parent, names}
+)
+
+// ValidFormat indica si el formato de géneros es reconocido
+func ValidFormat(format string) bool {
+	return format == FormatRaw || format == FormatCanonical || format == FormatObjects
+}
+
+// Entry es un género canónico o un grupo padre
+type Entry struct {
+	ID     string
+	Parent string
+	Names  map[string]string
+}
+
+// Taxonomy mapea los géneros del dataset a ids canónicos, grupos y nombres localizados
+type Taxonomy struct {
+	entries map[string]*Entry // id -> género
+	groups  map[string]*Entry // id -> grupo padre
+	aliases map[string]string // clave normalizada -> id
+	unknown map[string]int    // género sin entrada -> apariciones
+}
+
+// defaultGroups son los grupos padre de la taxonomía incluida
+var defaultGroups = []Entry{
+	{ID: "action-adventure", Names: map[string]string{"es": "Acción y aventura", "en": "Action & Adventure"}},
+	{ID: "comedy-family", Names: map[string]string{"es": "Comedia y familia", "en": "Comedy & Family"}},
+	{ID: "drama", Names: map[string]string{"es": "Drama", "en": "Drama"}},
+	{ID: "suspense", Names: map[string]string{"es": "Suspenso y crimen", "en": "Suspense & Crime"}},
+	{ID: "speculative", Names: map[string]string{"es": "Ficción especulativa", "en": "Speculative Fiction"}},
+	{ID: "non-fiction", Names: map[string]string{"es": "No ficción", "en": "Non-fiction"}},
+	{ID: "format", Names: map[string]string{"es": "Formato", "en": "Format"}},
+}
+
+// defaultEntries son los géneros de MovieLens (ml-latest, ml-25m, ml-100k y ml-1m)
+var defaultEntries = []struct {
+	Entry
+	aliases []string
+}{
+	{Entry{"action", "action-adventure", map[string]string{"es": "Acción", "en": "Action"}}, nil},
+	{Entry{"adventure", "action-adventure", map[string]string{"es": "Aventura", "en": "Adventure"}}, nil},
+	{Entry{"war", "action-adventure", map[string]string{"es": "Bélica", "en": "War"}}, nil},
+	{Entry{"western", "action-adventure", map[string]string{"es": "Western", "en": "Western"}}, nil},
+	{Entry{"animation", "comedy-family", map[string]string{"es": "Animación", "en": "Animation"}}, nil},
+	{Entry{"children", "comedy-family", map[string]string{"es": "Infantil", "en": "Children"}}, []string{"Children's", "Kids", "Family"}},
+	{Entry{"comedy", "comedy-family", map[string]string{"es": "Comedia", "en": "Comedy"}}, nil},
+	{Entry{"musical", "comedy-family", map[string]string{"es": "Musical", "en": "Musical"}}, []string{"Music"}},
+	{Entry{"drama", "drama", map[string]string{"es": "Drama", "en": "Drama"}}, nil},
+	{Entry{"romance", "drama", map[string]string{"es": "Romance", "en": "Romance"}}, nil},
+	{Entry{"crime", "suspense", map[string]string{"es": "Crimen", "en": "Crime"}}, nil},
+	{Entry{"film-noir", "suspense", map[string]string{"es": "Cine negro", "en": "Film Noir"}}, []string{"Noir"}},
+	{Entry{"horror", "suspense", map[string]string{"es": "Terror", "en": "Horror"}}, nil},
+	{Entry{"mystery", "suspense", map[string]string{"es": "Misterio", "en": "Mystery"}}, nil},
+	{Entry{"thriller", "suspense", map[string]string{"es": "Suspenso", "en": "Thriller"}}, nil},
+	{Entry{"fantasy", "speculative", map[string]string{"es": "Fantasía", "en": "Fantasy"}}, nil},
+	{Entry{"sci-fi", "speculative", map[string]string{"es": "Ciencia ficción", "en": "Science Fiction"}}, []string{"Science Fiction"}},
+	{Entry{"documentary", "non-fiction", map[string]string{"es": "Documental", "en": "Documentary"}}, nil},
+	{Entry{"imax", "format", map[string]string{"es": "IMAX", "en": "IMAX"}}, nil},
+}
+
+// Default devuelve la taxonomía incluida para los géneros de MovieLens
+func Default() *Taxonomy {
+	t := &Taxonomy{
+		entries: make(map[string]*Entry),
+		groups:  make(map[string]*Entry),
+		aliases: make(map[string]string),
+		unknown: make(map[string]int),
+	}
+	// Copias profundas: Load modifica los nombres y no debe alterar la taxonomía incluida
+	for i := range defaultGroups {
+		t.groups[defaultGroups[i].ID] = defaultGroups[i].clone()
+	}
+	for _, d := range defaultEntries {
+		e := d.Entry.clone()
+		t.add(e)
+		for _, alias := range d.aliases {
+			t.aliases[key(alias)] = e.ID
+		}
+	}
+	return t
+}
+
+// clone copia la entrada junto con su mapa de nombres
+func (e Entry) clone() *Entry {
+	names := make(map[string]string, len(e.Names))
+	for lang, name := range e.Names {
+		names[lang] = name
+	}
+	e.Names = names
+	return &e
+}
+
+// add registra un género; su id y sus nombres también se aceptan como alias
+func (t *Taxonomy) add(e *Entry) {
+	t.entries[e.ID] = e
+	t.aliases[key(e.ID)] = e.ID
+	for _, name := range e.Names {
+		if _, taken := t.aliases[key(name)]; !taken {
+			t.aliases[key(name)] = e.ID
+		}
+	}
+}
+
+// Load lee un archivo de taxonomía (CSV con columnas raw,id,parent,name_es,name_en,... y cualquier
+// otro name_<idioma>) y lo combina con la taxonomía incluida. Las filas sin raw definen grupos padre;
+// varias filas con el mismo id agregan alias.
+func Load(path string) (*Taxonomy, error) {
+	t := Default()
+	r, err := inputs.OpenCSV(path, "genre-taxonomy", "raw", "id")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	rawCol, idCol, parentCol := r.Index("raw"), r.Index("id"), r.Index("parent")
+	nameCols := make(map[string]int)
+	for i, h := range r.Header() {
+		if lang, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(h)), "name_"); ok && lang != "" {
+			nameCols[lang] = i
+		}
+	}
+
+	line := 1
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		id := strings.TrimSpace(inputs.Field(rec, idCol))
+		if id == "" {
+			return nil, fmt.Errorf("%s:%d: id vacío", path, line)
+		}
+		names := make(map[string]string)
+		for lang, col := range nameCols {
+			if name := strings.TrimSpace(inputs.Field(rec, col)); name != "" {
+				names[lang] = name
+			}
+		}
+		raw := strings.TrimSpace(inputs.Field(rec, rawCol))
+		if raw == "" {
+			t.groups[id] = &Entry{ID: id, Names: names}
+			continue
+		}
+
+		e := t.entries[id]
+		if e == nil {
+			e = &Entry{ID: id, Names: make(map[string]string)}
+		}
+		if parent := strings.TrimSpace(inputs.Field(rec, parentCol)); parent != "" {
+			e.Parent = parent
+		}
+		for lang, name := range names {
+			e.Names[lang] = name
+		}
+		t.add(e)
+		t.aliases[key(raw)] = id
+	}
+
+	for _, e := range t.entries {
+		if e.Parent != "" && t.groups[e.Parent] == nil {
+			return nil, fmt.Errorf("%s: el género %q referencia un grupo inexistente %q", path, e.ID, e.Parent)
+		}
+	}
+	return t, nil
+}
+
+// Lookup devuelve el género canónico de un nombre del dataset (sin distinguir mayúsculas,
+// espacios ni puntuación: "Sci-Fi", "sci fi" y "SciFi" son el mismo)
+func (t *Taxonomy) Lookup(raw string) (models.Genre, bool) {
+	id, ok := t.aliases[key(raw)]
+	if !ok {
+		return models.Genre{}, false
+	}
+	e := t.entries[id]
+	return models.Genre{ID: e.ID, Parent: e.Parent, Names: e.Names}, true
+}
+
+// Normalize convierte los géneros de una película y registra los desconocidos, que se conservan
+// con un id derivado del nombre y el nombre original en todos los idiomas
+func (t *Taxonomy) Normalize(raw []string) []models.Genre {
+	out := make([]models.Genre, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, name := range raw {
+		g, ok := t.Lookup(name)
+		if !ok {
+			t.unknown[name]++
+			g = models.Genre{ID: slug(name), Names: map[string]string{"es": name, "en": name}}
+		}
+		if !seen[g.ID] {
+			seen[g.ID] = true
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// Convert devuelve los géneros de una película en el formato indicado: los nombres (raw o ids
+// canónicos) y, en formato objects, los objetos para movies.ndjson
+func (t *Taxonomy) Convert(raw []string, format string) ([]string, []models.Genre) {
+	genres := t.Normalize(raw)
+	if format == FormatRaw {
+		return raw, nil
+	}
+	ids := make([]string, len(genres))
+	for i, g := range genres {
+		ids[i] = g.ID
+	}
+	if format == FormatObjects {
+		return ids, genres
+	}
+	return ids, nil
+}
+
+// IDs convierte nombres del dataset a ids canónicos sin registrarlos como desconocidos
+// (ej: los géneros preferidos de los usuarios)
+func (t *Taxonomy) IDs(raw []string) []string {
+	ids := make([]string, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, name := range raw {
+		id := slug(name)
+		if g, ok := t.Lookup(name); ok {
+			id = g.ID
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Names devuelve los nombres de todos los géneros en el formato indicado
+// (raw: nombre en inglés; canonical y objects: id), ordenados
+func (t *Taxonomy) Names(format string) []string {
+	names := make([]string, 0, len(t.entries))
+	for _, e := range t.entries {
+		if format == FormatRaw && e.Names["en"] != "" {
+			names = append(names, e.Names["en"])
+		} else {
+			names = append(names, e.ID)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Unknown devuelve los géneros sin entrada en la taxonomía y sus apariciones
+func (t *Taxonomy) Unknown() map[string]int {
+	return t.unknown
+}
+
+// Lines devuelve el resumen legible (consola y report.txt)
+func (t *Taxonomy) Lines(format string) []string {
+	lines := []string{fmt.Sprintf("  • Taxonomía: %d géneros en %d grupos (formato: %s)", len(t.entries), len(t.groups), format)}
+	if len(t.unknown) == 0 {
+		return append(lines, "  ✓ Todos los géneros del dataset están en la taxonomía")
+	}
+	names := make([]string, 0, len(t.unknown))
+	for name := range t.unknown {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if t.unknown[names[i]] != t.unknown[names[j]] {
+			return t.unknown[names[i]] > t.unknown[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	samples := make([]string, 0, len(names))
+	for _, name := range names {
+		samples = append(samples, fmt.Sprintf("%s (%d)", name, t.unknown[name]))
+	}
+	return append(lines, fmt.Sprintf("  ⚠ %d géneros desconocidos: %s", len(names), strings.Join(samples, ", ")))
+}
+
+// key normaliza un nombre de género para compararlo (minúsculas, solo letras y dígitos)
+func key(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// slug genera un id para géneros desconocidos ("Film Noir" -> "film-noir")
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
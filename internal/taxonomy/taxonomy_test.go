@@ -0,0 +1,33 @@
+package taxonomy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Load combina los nombres del archivo sobre una copia: la taxonomía incluida no debe cambiar
+func TestLoadKeepsDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "taxonomy.csv")
+	content := "raw,id,parent,name_es,name_en\n" +
+		",drama,,Dramas,Dramas\n" +
+		"Drama,drama,drama,Drama clásico,Classic Drama\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, _ := loaded.Lookup("Drama"); g.Names["es"] != "Drama clásico" {
+		t.Fatalf("Load: Names[es] = %q, want %q", g.Names["es"], "Drama clásico")
+	}
+
+	def := Default()
+	if g, _ := def.Lookup("Drama"); g.Names["es"] == "Drama clásico" || g.Names["en"] == "Classic Drama" {
+		t.Errorf("Default() modificado por Load: %v", g.Names)
+	}
+	if g := def.groups["drama"]; g.Names["es"] != "Drama" {
+		t.Errorf("grupo drama modificado por Load: %v", g.Names)
+	}
+}
@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MongoCollection describe una colección a importar: archivo NDJSON, validador e índices
+type MongoCollection struct {
+	Name      string
+	File      string
+	Validator string   // $jsonSchema en sintaxis mongosh (vacío = sin validador)
+	Indexes   []string // argumentos de createIndex en sintaxis mongosh
+}
+
+// mongoCollections contiene la definición de cada colección conocida por el ETL
+var mongoCollections = map[string]MongoCollection{
+	"movies": {
+		Name: "movies",
+		File: "movies.ndjson",
+		Validator: `{
+  bsonType: "object",
+  required: ["movieId", "title", "genres", "createdAt", "updatedAt"],
+  properties: {
+    movieId: { bsonType: "number" },
+    iIdx: { bsonType: "number" },
+    title: { bsonType: "string" },
+    sortTitle: { bsonType: "string" },
+    alternateTitles: { bsonType: "array" },
+    year: { bsonType: "number" },
+    endYear: { bsonType: "number" },
+    genres: { bsonType: "array" },
+    ratingStats: { bsonType: "object" },
+    externalData: { bsonType: "object" }
+  }
+}`,
+		Indexes: []string{
+			`{ movieId: 1 }, { unique: true }`,
+			`{ iIdx: 1 }`,
+			`{ title: "text", alternateTitles: "text" }`,
+			`{ sortTitle: 1 }`,
+		},
+	},
+	"ratings": {
+		Name: "ratings",
+		File: "ratings.ndjson",
+		Validator: `{
+  bsonType: "object",
+  required: ["userId", "movieId", "rating", "timestamp"],
+  properties: {
+    userId: { bsonType: "number" },
+    movieId: { bsonType: "number" },
+    rating: { bsonType: "number", minimum: 0 },
+    timestamp: { bsonType: "number" }
+  }
+}`,
+		Indexes: []string{
+			`{ userId: 1, movieId: 1 }, { unique: true }`,
+			`{ movieId: 1 }`,
+		},
+	},
+	"users": {
+		Name: "users",
+		File: "users.ndjson",
+		Validator: `{
+  bsonType: "object",
+  required: ["userId", "username", "email", "passwordHash", "role"],
+  properties: {
+    userId: { bsonType: "number" },
+    uIdx: { bsonType: "number" },
+    username: { bsonType: "string" },
+    email: { bsonType: "string" },
+    passwordHash: { bsonType: "string" },
+    role: { enum: ["user", "admin"] },
+    preferredGenres: { bsonType: "array" },
+    age: { bsonType: "number" }
+  }
+}`,
+		Indexes: []string{
+			`{ userId: 1 }, { unique: true }`,
+			`{ email: 1 }, { unique: true }`,
+			`{ uIdx: 1 }`,
+		},
+	},
+	"similarities": {
+		Name: "similarities",
+		File: "similarities.ndjson",
+		Validator: `{
+  bsonType: "object",
+  required: ["_id", "movieId", "iIdx", "metric", "k", "neighbors"],
+  properties: {
+    movieId: { bsonType: "number" },
+    iIdx: { bsonType: "number" },
+    metric: { bsonType: "string" },
+    k: { bsonType: "number" },
+    neighbors: { bsonType: "array" }
+  }
+}`,
+		Indexes: []string{
+			`{ iIdx: 1 }`,
+			`{ movieId: 1 }`,
+		},
+	},
+	"user_ratings": {
+		Name: "user_ratings",
+		File: "user_ratings.ndjson",
+		Validator: `{
+  bsonType: "object",
+  required: ["_id", "userId", "bucket", "count", "ratings"],
+  properties: {
+    userId: { bsonType: "number" },
+    uIdx: { bsonType: "number" },
+    bucket: { bsonType: "number" },
+    count: { bsonType: "number" },
+    minTimestamp: { bsonType: "number" },
+    maxTimestamp: { bsonType: "number" },
+    ratings: { bsonType: "array" }
+  }
+}`,
+		Indexes: []string{
+			`{ userId: 1, bucket: 1 }, { unique: true }`,
+			`{ uIdx: 1 }`,
+		},
+	},
+}
+
+var requiredRe = regexp.MustCompile(`required:\s*\[([^\]]*)\]`)
+
+// RequiredFields devuelve los campos requeridos por el validador $jsonSchema de la colección
+func RequiredFields(name string) []string {
+	m := requiredRe.FindStringSubmatch(mongoCollections[name].Validator)
+	if m == nil {
+		return nil
+	}
+	var fields []string
+	for _, f := range strings.Split(m[1], ",") {
+		if f = strings.Trim(strings.TrimSpace(f), `"`); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// MongoCollections devuelve las definiciones de las colecciones indicadas, en el mismo orden
+func MongoCollections(names []string) ([]MongoCollection, error) {
+	colls := make([]MongoCollection, 0, len(names))
+	for _, name := range names {
+		c, ok := mongoCollections[name]
+		if !ok {
+			return nil, fmt.Errorf("colección desconocida: %s", name)
+		}
+		colls = append(colls, c)
+	}
+	return colls, nil
+}
+
+// GenerateImportScripts escribe import.sh, import.ps1 y setup.js en outDir
+// para crear colecciones, validadores e índices e importar los NDJSON generados
+func GenerateImportScripts(outDir, dbName string, collections []string) ([]string, error) {
+	colls, err := MongoCollections(collections)
+	if err != nil {
+		return nil, err
+	}
+
+	setupPath := filepath.Join(outDir, "setup.js")
+	shPath := filepath.Join(outDir, "import.sh")
+	ps1Path := filepath.Join(outDir, "import.ps1")
+
+	if err := writeScript(setupPath, 0o644, func(w *bufio.Writer) { writeSetupJS(w, dbName, colls) }); err != nil {
+		return nil, err
+	}
+	if err := writeScript(shPath, 0o755, func(w *bufio.Writer) { writeImportSh(w, dbName, outDir, colls) }); err != nil {
+		return nil, err
+	}
+	if err := writeScript(ps1Path, 0o644, func(w *bufio.Writer) { writeImportPs1(w, dbName, outDir, colls) }); err != nil {
+		return nil, err
+	}
+
+	return []string{setupPath, shPath, ps1Path}, nil
+}
+
+// writeScript crea el archivo con los permisos indicados y delega el contenido a fill
+func writeScript(path string, perm os.FileMode, fill func(w *bufio.Writer)) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fill(w)
+	return w.Flush()
+}
+
+// writeSetupJS genera el script de mongosh (idempotente: crea o actualiza validadores)
+func writeSetupJS(w *bufio.Writer, dbName string, colls []MongoCollection) {
+	fmt.Fprintln(w, "// Generado por el ETL - crear colecciones, validadores e índices")
+	fmt.Fprintln(w, "// Uso: DB_NAME=movielens mongosh \"mongodb://localhost:27017\" --file setup.js")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "const dbName = (typeof process !== \"undefined\" && process.env.DB_NAME) || %q;\n", dbName)
+	fmt.Fprintln(w, "const target = db.getSiblingDB(dbName);")
+	fmt.Fprintln(w, "const existing = target.getCollectionNames();")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "function ensureCollection(name, schema) {")
+	fmt.Fprintln(w, "  const opts = schema ? { validator: { $jsonSchema: schema }, validationLevel: \"moderate\" } : {};")
+	fmt.Fprintln(w, "  if (existing.includes(name)) {")
+	fmt.Fprintln(w, "    if (schema) target.runCommand(Object.assign({ collMod: name }, opts));")
+	fmt.Fprintln(w, "  } else {")
+	fmt.Fprintln(w, "    target.createCollection(name, opts);")
+	fmt.Fprintln(w, "  }")
+	fmt.Fprintln(w, "  print(\"✓ \" + dbName + \".\" + name);")
+	fmt.Fprintln(w, "}")
+
+	for _, c := range colls {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "// %s\n", c.Name)
+		if c.Validator != "" {
+			fmt.Fprintf(w, "ensureCollection(%q, %s);\n", c.Name, c.Validator)
+		} else {
+			fmt.Fprintf(w, "ensureCollection(%q, null);\n", c.Name)
+		}
+		for _, idx := range c.Indexes {
+			fmt.Fprintf(w, "target.%s.createIndex(%s);\n", c.Name, idx)
+		}
+	}
+}
+
+// writeImportSh genera el script de importación para bash
+func writeImportSh(w *bufio.Writer, dbName, outDir string, colls []MongoCollection) {
+	fmt.Fprintln(w, "#!/usr/bin/env bash")
+	fmt.Fprintln(w, "# Generado por el ETL - importar colecciones a MongoDB")
+	fmt.Fprintln(w, "# Uso: ./import.sh [db] [out_dir]   (o variables DB, OUT_DIR, MONGO_URI)")
+	fmt.Fprintln(w, "set -euo pipefail")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "DB=\"${1:-${DB:-%s}}\"\n", dbName)
+	fmt.Fprintf(w, "OUT_DIR=\"${2:-${OUT_DIR:-%s}}\"\n", filepath.ToSlash(outDir))
+	fmt.Fprintln(w, "MONGO_URI=\"${MONGO_URI:-mongodb://localhost:27017}\"")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "DB_NAME=\"$DB\" mongosh \"$MONGO_URI\" --quiet --file \"$OUT_DIR/setup.js\"")
+	fmt.Fprintln(w)
+	for _, c := range colls {
+		fmt.Fprintf(w, "mongoimport --uri \"$MONGO_URI\" --db \"$DB\" --collection %s --file \"$OUT_DIR/%s\"\n", c.Name, c.File)
+	}
+}
+
+// writeImportPs1 genera el script de importación para PowerShell
+func writeImportPs1(w *bufio.Writer, dbName, outDir string, colls []MongoCollection) {
+	fmt.Fprintln(w, "# Generado por el ETL - importar colecciones a MongoDB")
+	fmt.Fprintln(w, "# Uso: .\\import.ps1 [-Db movielens] [-OutDir out] [-MongoUri mongodb://localhost:27017]")
+	fmt.Fprintln(w, "param(")
+	fmt.Fprintf(w, "    [string]$Db = %s,\n", psQuote(dbName))
+	fmt.Fprintf(w, "    [string]$OutDir = %s,\n", psQuote(outDir))
+	fmt.Fprintln(w, "    [string]$MongoUri = \"mongodb://localhost:27017\"")
+	fmt.Fprintln(w, ")")
+	fmt.Fprintln(w, "$ErrorActionPreference = \"Stop\"")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "$env:DB_NAME = $Db")
+	fmt.Fprintln(w, "mongosh $MongoUri --quiet --file (Join-Path $OutDir \"setup.js\")")
+	fmt.Fprintln(w, "if ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }")
+	fmt.Fprintln(w)
+	for _, c := range colls {
+		fmt.Fprintf(w, "mongoimport --uri $MongoUri --db $Db --collection %s --file (Join-Path $OutDir \"%s\")\n", c.Name, c.File)
+		fmt.Fprintln(w, "if ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }")
+	}
+}
+
+// psQuote escapa una cadena como literal de PowerShell entre comillas simples
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
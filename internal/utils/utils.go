@@ -50,8 +50,23 @@ func FormatDuration(d time.Duration) string {
 	return fmt.Sprintf("%ds", s)
 }
 
+// ExtraOutput describe una salida opcional del ETL (fuera de las cuatro colecciones principales)
+type ExtraOutput struct {
+	Label       string // etiqueta en la tabla de estadísticas (ej: "User buckets")
+	File        string // nombre del archivo dentro de out/
+	Description string
+	Count       int
+	Unit        string // unidad del conteo (default: "documentos generados")
+}
+
+// ReportSection es una sección adicional del reporte (título y líneas ya formateadas)
+type ReportSection struct {
+	Title string
+	Lines []string
+}
+
 // GenerateReport genera un archivo de reporte con estadísticas del ETL
-func GenerateReport(path string, moviesCount, ratingsCount, usersCount, similaritiesCount int, hashedPasswords, fetchedExternal bool, processedMovies, processedRatings, processedUsers, processedSimilarities bool, elapsed time.Duration) error {
+func GenerateReport(path, dbName string, moviesCount, ratingsCount, usersCount, similaritiesCount int, hashedPasswords, fetchedExternal bool, processedMovies, processedRatings, processedUsers, processedSimilarities bool, extras []ExtraOutput, sections []ReportSection, elapsed time.Duration) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return err
@@ -133,6 +148,13 @@ func GenerateReport(path string, moviesCount, ratingsCount, usersCount, similari
 	} else {
 		fmt.Fprintln(w, "  Similarities:  (no procesado)")
 	}
+	for _, extra := range extras {
+		unit := extra.Unit
+		if unit == "" {
+			unit = "documentos generados"
+		}
+		fmt.Fprintf(w, "  %-14s %10d %s\n", extra.Label+":", extra.Count, unit)
+	}
 	fmt.Fprintln(w, strings.Repeat("-", 80))
 	total := 0
 	if processedMovies {
@@ -166,29 +188,38 @@ func GenerateReport(path string, moviesCount, ratingsCount, usersCount, similari
 	if processedSimilarities {
 		fmt.Fprintln(w, "  • out/similarities.ndjson   - Similitudes coseno (k=20)")
 	}
+	for _, extra := range extras {
+		fmt.Fprintf(w, "  • %-26s - %s\n", "out/"+extra.File, extra.Description)
+	}
+	fmt.Fprintln(w, "  • out/setup.js              - Script mongosh (colecciones, validadores, índices)")
+	fmt.Fprintln(w, "  • out/import.sh             - Script de importación (bash)")
+	fmt.Fprintln(w, "  • out/import.ps1            - Script de importación (PowerShell)")
 	fmt.Fprintln(w, "  • out/report.txt            - Este reporte")
 	fmt.Fprintln(w)
 
-	// Comandos de importación (solo para procesadores ejecutados)
+	// Secciones adicionales (entradas, validaciones, etc.); se omiten las vacías
+	for _, section := range sections {
+		if len(section.Lines) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, section.Title+":")
+		fmt.Fprintln(w, strings.Repeat("-", 80))
+		for _, line := range section.Lines {
+			fmt.Fprintln(w, line)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Scripts de importación generados junto a los NDJSON
 	fmt.Fprintln(w, "IMPORTACIÓN A MONGODB:")
 	fmt.Fprintln(w, strings.Repeat("-", 80))
-	fmt.Fprintln(w, "Ejecutar los siguientes comandos en PowerShell:")
+	fmt.Fprintln(w, "Scripts generados (crean colecciones, validadores e índices y luego importan):")
 	fmt.Fprintln(w)
-	fmt.Fprintln(w, "  $DB = \"movielens\"")
-	fmt.Fprintln(w, "  $OUT_DIR = \"out\"")
+	fmt.Fprintln(w, "  • out/setup.js              - mongosh: colecciones, validadores e índices")
+	fmt.Fprintln(w, "  • out/import.sh             - bash:       ./import.sh [db] [out_dir]")
+	fmt.Fprintln(w, "  • out/import.ps1            - PowerShell: .\\import.ps1 -Db <db> -OutDir <dir>")
 	fmt.Fprintln(w)
-	if processedMovies {
-		fmt.Fprintln(w, "  mongoimport --db $DB --collection movies --file \"$OUT_DIR\\movies.ndjson\"")
-	}
-	if processedRatings {
-		fmt.Fprintln(w, "  mongoimport --db $DB --collection ratings --file \"$OUT_DIR\\ratings.ndjson\"")
-	}
-	if processedUsers {
-		fmt.Fprintln(w, "  mongoimport --db $DB --collection users --file \"$OUT_DIR\\users.ndjson\"")
-	}
-	if processedSimilarities {
-		fmt.Fprintln(w, "  mongoimport --db $DB --collection similarities --file \"$OUT_DIR\\similarities.ndjson\"")
-	}
+	fmt.Fprintf(w, "  Base de datos por defecto: %s\n", dbName)
 	fmt.Fprintln(w)
 
 	// Verificación
@@ -196,7 +227,7 @@ func GenerateReport(path string, moviesCount, ratingsCount, usersCount, similari
 	fmt.Fprintln(w, strings.Repeat("-", 80))
 	fmt.Fprintln(w, "Ejecutar en mongosh para verificar:")
 	fmt.Fprintln(w)
-	fmt.Fprintln(w, "  use movielens")
+	fmt.Fprintf(w, "  use %s\n", dbName)
 	if processedMovies {
 		fmt.Fprintf(w, "  db.movies.countDocuments()       // Esperado: %d\n", moviesCount)
 	}
@@ -211,26 +242,6 @@ func GenerateReport(path string, moviesCount, ratingsCount, usersCount, similari
 	}
 	fmt.Fprintln(w)
 
-	// Índices recomendados
-	fmt.Fprintln(w, "ÍNDICES RECOMENDADOS:")
-	fmt.Fprintln(w, strings.Repeat("-", 80))
-	if processedMovies {
-		fmt.Fprintln(w, "  db.movies.createIndex({ movieId: 1 })")
-		fmt.Fprintln(w, "  db.movies.createIndex({ iIdx: 1 })")
-		fmt.Fprintln(w, "  db.movies.createIndex({ title: \"text\" })")
-	}
-	if processedRatings {
-		fmt.Fprintln(w, "  db.ratings.createIndex({ userId: 1, movieId: 1 })")
-	}
-	if processedUsers {
-		fmt.Fprintln(w, "  db.users.createIndex({ userId: 1 }, { unique: true })")
-		fmt.Fprintln(w, "  db.users.createIndex({ email: 1 }, { unique: true })")
-	}
-	if processedSimilarities {
-		fmt.Fprintln(w, "  db.similarities.createIndex({ iIdx: 1 })")
-	}
-	fmt.Fprintln(w)
-
 	// Notas finales
 	fmt.Fprintln(w, "NOTAS:")
 	fmt.Fprintln(w, strings.Repeat("-", 80))
@@ -0,0 +1,294 @@
+package validation
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pc4_etl/internal/inputs"
+)
+
+// Políticas ante claves duplicadas
+const (
+	DupKeepFirst  = "keep-first"            // conservar la primera aparición
+	DupKeepLast   = "keep-last"             // conservar la última aparición
+	DupKeepLatest = "keep-latest-timestamp" // conservar la de mayor timestamp (sin timestamp = keep-last)
+	DupFail       = "fail"                  // abortar si hay duplicados
+)
+
+// ValidDuplicatePolicy indica si la política de duplicados es reconocida
+func ValidDuplicatePolicy(policy string) bool {
+	switch policy {
+	case DupKeepFirst, DupKeepLast, DupKeepLatest, DupFail:
+		return true
+	}
+	return false
+}
+
+// DuplicateCheck es el resultado de buscar claves repetidas en un archivo
+type DuplicateCheck struct {
+	Name       string   `json:"name"`
+	Key        string   `json:"key"`
+	Rows       int      `json:"rows"`
+	Keys       int      `json:"keys"`
+	Collisions int      `json:"collisions"` // claves con más de una fila
+	Duplicates int      `json:"duplicates"` // filas sobrantes (descartadas al resolver)
+	Invalid    int      `json:"invalid,omitempty"` // filas ilegibles o con clave no numérica o fuera de rango
+	Samples    []string `json:"samples,omitempty"`
+	Skipped    string   `json:"skipped,omitempty"`
+	Resolved   string   `json:"resolved,omitempty"` // CSV deduplicado que reemplaza a la entrada
+}
+
+// DuplicateReport agrupa la detección de duplicados de todas las entradas con clave
+type DuplicateReport struct {
+	Policy string            `json:"policy"`
+	Checks []*DuplicateCheck `json:"checks"`
+}
+
+// Total devuelve el total de filas duplicadas
+func (r *DuplicateReport) Total() int {
+	total := 0
+	for _, c := range r.Checks {
+		total += c.Duplicates
+	}
+	return total
+}
+
+// Lines devuelve el resumen legible (consola y report.txt)
+func (r *DuplicateReport) Lines() []string {
+	var lines []string
+	for _, c := range r.Checks {
+		switch {
+		case c.Skipped != "":
+			lines = append(lines, fmt.Sprintf("  ⏭ %s (omitido: %s)", c.Name, c.Skipped))
+		case c.Duplicates == 0:
+			lines = append(lines, fmt.Sprintf("  ✓ %s: sin duplicados en %d filas", c.Name, c.Rows))
+		default:
+			lines = append(lines, fmt.Sprintf("  ✗ %s: %d claves repetidas, %d filas sobrantes de %d (ej: %s)",
+				c.Name, c.Collisions, c.Duplicates, c.Rows, strings.Join(c.Samples, "; ")))
+		}
+		if c.Invalid > 0 {
+			lines = append(lines, fmt.Sprintf("    ⚠ %d filas sin clave válida (no se verifican)", c.Invalid))
+		}
+	}
+	return lines
+}
+
+// WriteJSON guarda el reporte completo en formato JSON
+func (r *DuplicateReport) WriteJSON(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// keyedInput describe una entrada con clave (una o dos columnas enteras)
+type keyedInput struct {
+	name      string
+	kind      string
+	path      *string
+	keys      []string
+	timestamp string // columna para keep-latest-timestamp ("" = no tiene)
+}
+
+// CheckDuplicates busca claves repetidas en movies, ratings, links, genome-tags y genome-scores.
+// Si stagingDir no está vacío y la política no es fail, escribe allí un CSV deduplicado por cada
+// entrada con duplicados y devuelve files con esas rutas en lugar de las originales.
+func CheckDuplicates(files InputFiles, policy string, samples int, stagingDir string) (*DuplicateReport, InputFiles, error) {
+	rep := &DuplicateReport{Policy: policy}
+	resolved := files
+	keyed := []keyedInput{
+		{"movies (movieId)", "movies", &resolved.Movies, []string{"movieId"}, ""},
+		{"ratings (userId, movieId)", "ratings", &resolved.Ratings, []string{"userId", "movieId"}, "timestamp"},
+		{"links (movieId)", "links", &resolved.Links, []string{"movieId"}, ""},
+		{"genome-tags (tagId)", "genome-tags", &resolved.GenomeTags, []string{"tagId"}, ""},
+		{"genome-scores (movieId, tagId)", "genome-scores", &resolved.GenomeScores, []string{"movieId", "tagId"}, ""},
+	}
+	for _, in := range keyed {
+		c := &DuplicateCheck{Name: in.name, Key: strings.Join(in.keys, ",")}
+		rep.Checks = append(rep.Checks, c)
+		if *in.path == "" {
+			c.Skipped = reason(os.ErrNotExist)
+			continue
+		}
+		keep, err := findDuplicates(*in.path, in, policy, samples, c)
+		if err != nil {
+			c.Skipped = reason(err)
+			continue
+		}
+		if c.Duplicates == 0 || stagingDir == "" || policy == DupFail {
+			continue
+		}
+		out := filepath.Join(stagingDir, in.kind+".csv")
+		if err := writeDeduplicated(*in.path, out, in, keep); err != nil {
+			return rep, files, fmt.Errorf("%s: %w", in.name, err)
+		}
+		c.Resolved = out
+		*in.path = out
+	}
+	return rep, resolved, nil
+}
+
+// keptRow es la fila elegida para una clave
+type keptRow struct {
+	row   int
+	ts    int64
+	count int
+}
+
+// rowKey empaqueta hasta dos columnas enteras en una clave (ok=false si no son numéricas o
+// quedan fuera de 0..2^32-1, ya que se truncarían y colisionarían con otras claves)
+func rowKey(rec []string, idx []int) (uint64, bool) {
+	var key uint64
+	for _, i := range idx {
+		v, err := strconv.ParseUint(strings.TrimSpace(inputs.Field(rec, i)), 10, 32)
+		if err != nil {
+			return 0, false
+		}
+		key = key<<32 | v
+	}
+	return key, true
+}
+
+// findDuplicates recorre la entrada y elige, según la política, la fila que se conserva por clave
+func findDuplicates(path string, in keyedInput, policy string, samples int, c *DuplicateCheck) (map[uint64]*keptRow, error) {
+	r, err := inputs.OpenCSV(path, in.kind, in.keys...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	idx := make([]int, len(in.keys))
+	for i, k := range in.keys {
+		idx[i] = r.Index(k)
+	}
+	tsCol := -1
+	if in.timestamp != "" {
+		tsCol = r.Index(in.timestamp)
+	}
+
+	keep := make(map[uint64]*keptRow)
+	collisions := make(map[uint64][]int) // filas de las claves de muestra
+	var order []uint64
+	row := 0
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.Invalid++
+			continue
+		}
+		row++
+		c.Rows++
+		key, ok := rowKey(rec, idx)
+		if !ok {
+			c.Invalid++
+			continue
+		}
+		ts, _ := strconv.ParseInt(strings.TrimSpace(inputs.Field(rec, tsCol)), 10, 64)
+
+		k := keep[key]
+		if k == nil {
+			keep[key] = &keptRow{row: row, ts: ts, count: 1}
+			continue
+		}
+		k.count++
+		c.Duplicates++
+		if k.count == 2 {
+			c.Collisions++
+			if len(order) < samples {
+				order = append(order, key)
+				collisions[key] = []int{k.row}
+			}
+		}
+		if rows, sampled := collisions[key]; sampled {
+			collisions[key] = append(rows, row)
+		}
+		switch {
+		case policy == DupKeepLast, policy == DupKeepLatest && tsCol < 0:
+			k.row, k.ts = row, ts
+		case policy == DupKeepLatest && ts >= k.ts:
+			k.row, k.ts = row, ts
+		}
+	}
+	c.Keys = len(keep)
+
+	for _, key := range order {
+		rows := collisions[key]
+		parts := make([]string, len(rows))
+		for i, r := range rows {
+			parts[i] = strconv.Itoa(r)
+		}
+		sample := fmt.Sprintf("%s filas %s", describeKey(key, in.keys), strings.Join(parts, ","))
+		if policy != DupFail {
+			sample += fmt.Sprintf(" -> fila %d", keep[key].row)
+		}
+		c.Samples = append(c.Samples, sample)
+	}
+	return keep, nil
+}
+
+// describeKey formatea la clave empaquetada (ej: "userId=1 movieId=2")
+func describeKey(key uint64, names []string) string {
+	parts := make([]string, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		parts[i] = fmt.Sprintf("%s=%d", names[i], uint32(key))
+		key >>= 32
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeDeduplicated copia la entrada a outPath conservando solo las filas elegidas; las filas sin
+// clave válida se copian tal cual y las ilegibles (ya contadas en Invalid) no se pueden copiar
+func writeDeduplicated(path, outPath string, in keyedInput, keep map[uint64]*keptRow) error {
+	r, err := inputs.OpenCSV(path, in.kind, in.keys...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	idx := make([]int, len(in.keys))
+	for i, k := range in.keys {
+		idx[i] = r.Index(k)
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	w := csv.NewWriter(bw)
+	if err := w.Write(r.Header()); err != nil {
+		return err
+	}
+	row := 0
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		row++
+		if key, ok := rowKey(rec, idx); ok && keep[key].row != row {
+			continue
+		}
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,86 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDuplicatesPolicies(t *testing.T) {
+	const ratings = "userId,movieId,rating,timestamp\n" +
+		"1,1,1.0,30\n" +
+		"1,2,2.0,10\n" +
+		"1,1,3.0,50\n" +
+		"1,1,4.0,20\n"
+	for policy, want := range map[string]string{
+		DupKeepFirst:  "1,1,1.0,30",
+		DupKeepLast:   "1,1,4.0,20",
+		DupKeepLatest: "1,1,3.0,50",
+	} {
+		t.Run(policy, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "ratings.csv")
+			if err := os.WriteFile(path, []byte(ratings), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			staging := filepath.Join(dir, "staging")
+			if err := os.Mkdir(staging, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			rep, resolved, err := CheckDuplicates(InputFiles{Ratings: path}, policy, 5, staging)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := rep.Checks[1]
+			if c.Rows != 4 || c.Keys != 2 || c.Collisions != 1 || c.Duplicates != 2 {
+				t.Fatalf("rows=%d keys=%d collisions=%d duplicates=%d", c.Rows, c.Keys, c.Collisions, c.Duplicates)
+			}
+			data, err := os.ReadFile(resolved.Ratings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(lines) != 3 || !strings.Contains(string(data), want+"\n") || !strings.Contains(string(data), "1,2,2.0,10\n") {
+				t.Errorf("CSV deduplicado:\n%s\nwant fila %s", data, want)
+			}
+		})
+	}
+}
+
+// Los ids negativos o mayores que 2^32-1 no se truncan (colisionarían con otros): se cuentan como
+// inválidos y se copian sin deduplicar
+func TestCheckDuplicatesInvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "movies.csv")
+	content := "movieId,title,genres\n" +
+		"1,A,Drama\n" +
+		"4294967297,B,Drama\n" +
+		"-1,C,Drama\n" +
+		"x,D,Drama\n" +
+		"1,E,Drama\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	staging := t.TempDir()
+	rep, resolved, err := CheckDuplicates(InputFiles{Movies: path}, DupKeepFirst, 5, staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := rep.Checks[0]
+	if c.Rows != 5 || c.Invalid != 3 || c.Duplicates != 1 {
+		t.Fatalf("rows=%d invalid=%d duplicates=%d\n%v", c.Rows, c.Invalid, c.Duplicates, rep.Lines())
+	}
+	if !strings.Contains(strings.Join(rep.Lines(), "\n"), "3 filas sin clave válida") {
+		t.Errorf("Lines() no reporta las filas inválidas: %v", rep.Lines())
+	}
+	data, err := os.ReadFile(resolved.Movies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "\n"); got != 5 {
+		t.Errorf("CSV deduplicado con %d líneas, want 5:\n%s", got, data)
+	}
+}
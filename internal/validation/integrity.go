@@ -0,0 +1,430 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pc4_etl/internal/inputs"
+	"pc4_etl/internal/loaders"
+	"pc4_etl/internal/models"
+)
+
+// Políticas ante referencias huérfanas
+const (
+	PolicyKeep = "keep" // solo reportar
+	PolicyDrop = "drop" // descartar ratings de películas inexistentes y similitudes sin item_map
+	PolicyFail = "fail" // abortar si hay huérfanos
+)
+
+// Severidad de un chequeo
+const (
+	SeverityError = "error" // referencia colgante (ej: rating de una película inexistente)
+	SeverityInfo  = "info"  // dirección inversa (ej: película sin ratings) o mapeo más amplio que el dataset, no es un error
+)
+
+// ValidPolicy indica si la política es reconocida
+func ValidPolicy(policy string) bool {
+	return policy == PolicyKeep || policy == PolicyDrop || policy == PolicyFail
+}
+
+// Check es el resultado de un chequeo de integridad en una dirección
+type Check struct {
+	Name     string   `json:"name"`
+	Severity string   `json:"severity"`
+	Checked  int      `json:"checked"`
+	Orphans  int      `json:"orphans"`
+	Samples  []string `json:"samples,omitempty"`
+	Skipped  string   `json:"skipped,omitempty"` // motivo si no se pudo ejecutar (ej: archivo ausente)
+	limit    int
+}
+
+// add cuenta un huérfano y guarda la muestra si aún hay lugar
+func (c *Check) add(sample string) {
+	c.Orphans++
+	if len(c.Samples) < c.limit {
+		c.Samples = append(c.Samples, sample)
+	}
+}
+
+// IntegrityReport agrupa los chequeos de integridad referencial
+type IntegrityReport struct {
+	Scope  string   `json:"scope"` // "inputs" u "outputs"
+	Checks []*Check `json:"checks"`
+	movies map[int]struct{}
+	limit  int
+}
+
+func newReport(scope string, samples int) *IntegrityReport {
+	return &IntegrityReport{Scope: scope, limit: samples}
+}
+
+func (r *IntegrityReport) check(name, severity string) *Check {
+	c := &Check{Name: name, Severity: severity, limit: r.limit}
+	r.Checks = append(r.Checks, c)
+	return c
+}
+
+// skip registra chequeos que no pudieron ejecutarse
+func (r *IntegrityReport) skip(reason string, names ...string) {
+	for _, name := range names {
+		c := r.check(name, SeverityError)
+		c.Skipped = reason
+	}
+}
+
+// Errors devuelve el total de huérfanos en chequeos de severidad error
+func (r *IntegrityReport) Errors() int {
+	total := 0
+	for _, c := range r.Checks {
+		if c.Severity == SeverityError {
+			total += c.Orphans
+		}
+	}
+	return total
+}
+
+// Lines devuelve el resumen legible (consola y report.txt)
+func (r *IntegrityReport) Lines() []string {
+	var lines []string
+	for _, c := range r.Checks {
+		switch {
+		case c.Skipped != "":
+			lines = append(lines, fmt.Sprintf("  ⏭ %s (omitido: %s)", c.Name, c.Skipped))
+		case c.Orphans == 0:
+			lines = append(lines, fmt.Sprintf("  ✓ %s: 0 de %d", c.Name, c.Checked))
+		default:
+			mark := "✗"
+			if c.Severity == SeverityInfo {
+				mark = "•"
+			}
+			lines = append(lines, fmt.Sprintf("  %s %s: %d de %d (ej: %s)", mark, c.Name, c.Orphans, c.Checked, strings.Join(c.Samples, "; ")))
+		}
+	}
+	return lines
+}
+
+// WriteJSON guarda el reporte completo en formato JSON
+func (r *IntegrityReport) WriteJSON(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// WriteReportsJSON guarda varios reportes (ej: entradas y salidas) como un arreglo JSON
+func WriteReportsJSON(path string, reports []*IntegrityReport) error {
+	b, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// MovieFilter devuelve un models.RatingFilter que descarta ratings de películas ausentes en
+// movies.csv (política drop); nil si no se pudo cargar movies.csv
+func (r *IntegrityReport) MovieFilter() models.RatingFilter {
+	if r.movies == nil {
+		return nil
+	}
+	return knownMovies(r.movies)
+}
+
+type knownMovies map[int]struct{}
+
+func (k knownMovies) Keep(doc *models.RatingDoc) bool {
+	_, ok := k[doc.MovieID]
+	return ok
+}
+
+// InputFiles son las rutas de los CSV de entrada a verificar (vacío = omitir)
+type InputFiles struct {
+	Movies       string
+	Ratings      string
+	Links        string
+	Tags         string
+	GenomeTags   string
+	GenomeScores string
+	ItemMap      string
+	UserMap      string
+	Similarities string
+}
+
+// CheckInputs verifica la integridad referencial entre los CSV de entrada en ambas direcciones
+// (ej: ratings -> movies y movies -> ratings), guardando hasta samples ejemplos por chequeo
+func CheckInputs(files InputFiles, samples int) *IntegrityReport {
+	rep := newReport("inputs", samples)
+
+	movies, err := idSet(files.Movies, "movies", "movieId")
+	if err != nil {
+		rep.skip(reason(err), "ratings.movieId -> movies", "links.movieId -> movies", "tags.movieId -> movies",
+			"genome-scores.movieId -> movies", "item_map.movieId -> movies")
+	} else {
+		rep.movies = movies
+	}
+
+	// ratings -> movies (y se recolectan películas y usuarios con ratings)
+	ratedMovies := make(map[int]struct{})
+	ratingUsers := make(map[int]struct{})
+	if movies != nil {
+		c := rep.check("ratings.movieId -> movies", SeverityError)
+		err := scanCSV(files.Ratings, "ratings", []string{"userId", "movieId"}, func(v []int) {
+			c.Checked++
+			ratingUsers[v[0]] = struct{}{}
+			ratedMovies[v[1]] = struct{}{}
+			if _, ok := movies[v[1]]; !ok {
+				c.add(fmt.Sprintf("userId=%d movieId=%d", v[0], v[1]))
+			}
+		})
+		if err != nil {
+			c.Skipped = reason(err)
+			ratedMovies, ratingUsers = nil, nil
+		}
+	} else if err := scanCSV(files.Ratings, "ratings", []string{"userId", "movieId"}, func(v []int) {
+		ratingUsers[v[0]] = struct{}{}
+	}); err != nil {
+		ratingUsers = nil
+	}
+
+	if movies != nil {
+		rep.dangling(files.Links, "links", "movieId", "links.movieId -> movies", SeverityError, movies)
+		rep.dangling(files.Tags, "tags", "movieId", "tags.movieId -> movies", SeverityError, movies)
+		rep.dangling(files.GenomeScores, "genome-scores", "movieId", "genome-scores.movieId -> movies", SeverityError, movies)
+		// Un item_map completo usado con un subconjunto de movies.csv es válido: informativo,
+		// igual que "dataset sin idx" en CheckMappings
+		rep.dangling(files.ItemMap, "item_map", "movieId", "item_map.movieId -> movies", SeverityInfo, movies)
+	}
+
+	if tags, err := idSet(files.GenomeTags, "genome-tags", "tagId"); err != nil {
+		rep.skip(reason(err), "genome-scores.tagId -> genome-tags")
+	} else {
+		rep.dangling(files.GenomeScores, "genome-scores", "tagId", "genome-scores.tagId -> genome-tags", SeverityError, tags)
+	}
+
+	// similarities -> item_map (por iIdx)
+	items, err := valueSet(files.ItemMap, "item_map", "movieId", "iIdx")
+	if err != nil {
+		rep.skip(reason(err), "similarities.iIdx -> item_map", "similarities.neighborIdx -> item_map")
+	} else {
+		rep.dangling(files.Similarities, "similarities", "iIdx", "similarities.iIdx -> item_map", SeverityError, items)
+		rep.dangling(files.Similarities, "similarities", "neighborIdx", "similarities.neighborIdx -> item_map", SeverityError, items)
+	}
+
+	// user_map <-> ratings
+	if ratingUsers != nil {
+		rep.dangling(files.UserMap, "user_map", "userId", "user_map.userId -> ratings", SeverityInfo, ratingUsers)
+		if users, err := idSet(files.UserMap, "user_map", "userId"); err == nil {
+			rep.missing("ratings.userId sin uIdx en user_map", ratingUsers, users)
+		}
+	}
+
+	// Dirección inversa: películas sin ratings, links o iIdx
+	if movies != nil {
+		if ratedMovies != nil {
+			rep.missing("movies sin ratings", movies, ratedMovies)
+		}
+		if linked, err := idSet(files.Links, "links", "movieId"); err == nil {
+			rep.missing("movies sin links", movies, linked)
+		}
+		if mapped, err := idSet(files.ItemMap, "item_map", "movieId"); err == nil {
+			rep.missing("movies sin iIdx en item_map", movies, mapped)
+		}
+	}
+	return rep
+}
+
+// CheckOutputs verifica la integridad referencial entre los NDJSON generados en outDir
+func CheckOutputs(outDir string, samples int) *IntegrityReport {
+	rep := newReport("outputs", samples)
+	path := func(name string) string { return filepath.Join(outDir, name) }
+
+	movies := make(map[int]struct{})
+	err := loaders.ReadNDJSON(path("movies.ndjson"), func(line []byte) error {
+		var doc models.MovieDoc
+		if err := loaders.DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		movies[doc.MovieID] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		rep.skip(reason(err), "ratings.movieId -> movies", "similarities.movieId -> movies", "similarities.neighbors -> movies")
+		return rep
+	}
+
+	users := make(map[int]struct{})
+	usersErr := loaders.ReadNDJSON(path("users.ndjson"), func(line []byte) error {
+		var doc models.UserDoc
+		if err := loaders.DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		users[doc.UserID] = struct{}{}
+		return nil
+	})
+
+	rated := make(map[int]struct{})
+	movieCheck := rep.check("ratings.movieId -> movies", SeverityError)
+	userCheck := rep.check("ratings.userId -> users", SeverityError)
+	if usersErr != nil {
+		userCheck.Skipped = reason(usersErr)
+	}
+	err = loaders.ReadNDJSON(path("ratings.ndjson"), func(line []byte) error {
+		var doc models.RatingDoc
+		if err := loaders.DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		movieCheck.Checked++
+		rated[doc.MovieID] = struct{}{}
+		if _, ok := movies[doc.MovieID]; !ok {
+			movieCheck.add(fmt.Sprintf("userId=%d movieId=%d", doc.UserID, doc.MovieID))
+		}
+		if usersErr == nil {
+			userCheck.Checked++
+			if _, ok := users[doc.UserID]; !ok {
+				userCheck.add(fmt.Sprintf("userId=%d movieId=%d", doc.UserID, doc.MovieID))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		movieCheck.Skipped, userCheck.Skipped = reason(err), reason(err)
+		rated = nil
+	}
+
+	simCheck := rep.check("similarities.movieId -> movies", SeverityError)
+	neighborCheck := rep.check("similarities.neighbors -> movies", SeverityError)
+	err = loaders.ReadNDJSON(path("similarities.ndjson"), func(line []byte) error {
+		var doc models.SimilarityDoc
+		if err := loaders.DecodeDoc(line, &doc); err != nil {
+			return err
+		}
+		simCheck.Checked++
+		if _, ok := movies[doc.MovieID]; !ok {
+			simCheck.add(fmt.Sprintf("_id=%s movieId=%d", doc.ID, doc.MovieID))
+		}
+		for _, n := range doc.Neighbors {
+			neighborCheck.Checked++
+			if _, ok := movies[n.MovieID]; !ok {
+				neighborCheck.add(fmt.Sprintf("_id=%s neighbor movieId=%d iIdx=%d", doc.ID, n.MovieID, n.IIdx))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		simCheck.Skipped, neighborCheck.Skipped = reason(err), reason(err)
+	}
+
+	if rated != nil {
+		rep.missing("movies sin ratings", movies, rated)
+	}
+	return rep
+}
+
+// dangling cuenta las filas de path cuya columna no está en known
+func (r *IntegrityReport) dangling(path, kind, column, name, severity string, known map[int]struct{}) {
+	c := r.check(name, severity)
+	err := scanCSV(path, kind, []string{column}, func(v []int) {
+		c.Checked++
+		if _, ok := known[v[0]]; !ok {
+			c.add(fmt.Sprintf("%s=%d", column, v[0]))
+		}
+	})
+	if err != nil {
+		c.Skipped = reason(err)
+	}
+}
+
+// missing cuenta los ids de from que no aparecen en to (dirección inversa, informativo)
+func (r *IntegrityReport) missing(name string, from, to map[int]struct{}) {
+	c := r.check(name, SeverityInfo)
+	ids := make([]int, 0, len(from))
+	for id := range from {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		c.Checked++
+		if _, ok := to[id]; !ok {
+			c.add(strconv.Itoa(id))
+		}
+	}
+}
+
+// scanCSV llama a fn con los valores enteros de las columnas indicadas (filas no numéricas se omiten)
+func scanCSV(path, kind string, columns []string, fn func([]int)) error {
+	if path == "" {
+		return os.ErrNotExist
+	}
+	r, err := inputs.OpenCSV(path, kind, columns...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	idx := make([]int, len(columns))
+	for i, col := range columns {
+		idx[i] = r.Index(col)
+	}
+
+	values := make([]int, len(columns))
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			continue
+		}
+		ok := true
+		for i, col := range idx {
+			v, err := strconv.Atoi(inputs.Field(rec, col))
+			if err != nil {
+				ok = false
+				break
+			}
+			values[i] = v
+		}
+		if ok {
+			fn(values)
+		}
+	}
+}
+
+// idSet devuelve el conjunto de valores de una columna (nil si no se pudo leer)
+func idSet(path, kind, column string) (map[int]struct{}, error) {
+	set := make(map[int]struct{})
+	err := scanCSV(path, kind, []string{column}, func(v []int) {
+		set[v[0]] = struct{}{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// valueSet devuelve el conjunto de valores de la columna value (ej: los iIdx de item_map.csv;
+// nil si no se pudo leer)
+func valueSet(path, kind, key, value string) (map[int]struct{}, error) {
+	set := make(map[int]struct{})
+	err := scanCSV(path, kind, []string{key, value}, func(v []int) {
+		set[v[1]] = struct{}{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// reason resume el motivo por el que un chequeo no pudo ejecutarse
+func reason(err error) string {
+	if errors.Is(err, os.ErrNotExist) {
+		return "archivo no encontrado"
+	}
+	return err.Error()
+}
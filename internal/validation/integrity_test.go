@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Si movies.csv no se puede leer (ausente o sin columna movieId), los chequeos hacia movies se
+// omiten una sola vez y no cuentan cada fila como huérfana
+func TestCheckInputsWithoutMovies(t *testing.T) {
+	for name, movies := range map[string]string{
+		"ausente":         "",
+		"sin columna":     "id,title,genres\n1,Toy Story (1995),Animation\n",
+		"sin columna bom": "\ufefftitle,genres\nToy Story (1995),Animation\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			write := func(file, content string) string {
+				path := filepath.Join(dir, file)
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			}
+			files := InputFiles{
+				Movies:  filepath.Join(dir, "movies.csv"),
+				Ratings: write("ratings.csv", "userId,movieId,rating,timestamp\n1,1,4.0,1\n1,2,3.5,2\n"),
+				Links:   write("links.csv", "movieId,imdbId,tmdbId\n1,114709,862\n"),
+			}
+			if movies != "" {
+				write("movies.csv", movies)
+			}
+
+			rep := CheckInputs(files, 5)
+			if got := rep.Errors(); got != 0 {
+				t.Errorf("Errors() = %d, want 0\n%v", got, rep.Lines())
+			}
+			seen := make(map[string]int)
+			for _, c := range rep.Checks {
+				seen[c.Name]++
+			}
+			for _, name := range []string{"ratings.movieId -> movies", "links.movieId -> movies"} {
+				if seen[name] != 1 {
+					t.Errorf("%q aparece %d veces, want 1", name, seen[name])
+				}
+			}
+		})
+	}
+}
+
+// Un item_map/user_map completo usado con un subconjunto del dataset no es un error (--orphans
+// fail no debe abortar); un rating de una película inexistente sí
+func TestCheckInputsSubsetWithFullMaps(t *testing.T) {
+	dir := t.TempDir()
+	write := func(file, content string) string {
+		path := filepath.Join(dir, file)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	files := InputFiles{
+		Movies:  write("movies.csv", "movieId,title,genres\n1,Toy Story (1995),Animation\n"),
+		Ratings: write("ratings.csv", "userId,movieId,rating,timestamp\n1,1,4.0,1\n"),
+		ItemMap: write("item_map.csv", "movieId,iIdx\n1,0\n2,1\n3,2\n"),
+		UserMap: write("user_map.csv", "userId,uIdx\n1,0\n2,1\n"),
+	}
+	rep := CheckInputs(files, 5)
+	if got := rep.Errors(); got != 0 {
+		t.Errorf("Errors() = %d, want 0\n%v", got, rep.Lines())
+	}
+	for _, c := range rep.Checks {
+		if (c.Name == "item_map.movieId -> movies" && c.Orphans != 2) || (c.Name == "user_map.userId -> ratings" && c.Orphans != 1) {
+			t.Errorf("%s: %d huérfanos (severidad %s)", c.Name, c.Orphans, c.Severity)
+		}
+	}
+
+	write("ratings.csv", "userId,movieId,rating,timestamp\n1,1,4.0,1\n1,9,3.0,2\n")
+	if got := CheckInputs(files, 5).Errors(); got != 1 {
+		t.Errorf("Errors() con rating huérfano = %d, want 1", got)
+	}
+}
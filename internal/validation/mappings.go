@@ -0,0 +1,162 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Mapping es un mapeo id -> índice a verificar (item_map.csv o user_map.csv)
+type Mapping struct {
+	Name      string           // item_map / user_map
+	IDColumn  string           // movieId / userId
+	IdxColumn string           // iIdx / uIdx
+	Map       map[int]int      // id -> índice
+	Dataset   map[int]struct{} // ids del dataset actual (nil = no comparar)
+	Skipped   string           // motivo si no se pudieron cargar los ids del dataset
+}
+
+// DatasetIDs devuelve el conjunto de valores de una columna de un CSV (ej: movieId de movies.csv)
+func DatasetIDs(path, kind, column string) (map[int]struct{}, error) {
+	return idSet(path, kind, column)
+}
+
+// CheckMappings verifica que cada mapeo sea un rango compacto de índices desde base (0 o 1): sin
+// índices menores que base, sin índices compartidos por varios ids y sin huecos; con el dataset,
+// también ids del mapeo que ya no existen (error) e ids del dataset sin índice (informativo). Si
+// similarities no es vacío, verifica además que iIdx/neighborIdx de ese CSV respeten la base
+func CheckMappings(maps []Mapping, base int, similarities string, samples int) *IntegrityReport {
+	rep := newReport("mappings", samples)
+	for _, m := range maps {
+		rep.checkMapping(m, base)
+	}
+	if similarities != "" {
+		// Con item_map, cada índice debe además pertenecer a una película (un item_map compactado
+		// sin remapear las similitudes asociaría vecinos de otras películas)
+		var items map[int]struct{}
+		for _, m := range maps {
+			if m.Name == "item_map" {
+				items = make(map[int]struct{}, len(m.Map))
+				for _, idx := range m.Map {
+					items[idx] = struct{}{}
+				}
+			}
+		}
+		c := rep.check(fmt.Sprintf("similarities.iIdx/neighborIdx >= %d", base), SeverityError)
+		var member *Check
+		if items != nil {
+			member = rep.check("similarities.iIdx/neighborIdx -> item_map", SeverityError)
+		}
+		err := scanCSV(similarities, "similarities", []string{"iIdx", "neighborIdx"}, func(v []int) {
+			c.Checked++
+			if v[0] < base || v[1] < base {
+				c.add(fmt.Sprintf("iIdx=%d neighborIdx=%d", v[0], v[1]))
+			}
+			if member != nil {
+				member.Checked++
+				_, iok := items[v[0]]
+				_, jok := items[v[1]]
+				if !iok || !jok {
+					member.add(fmt.Sprintf("iIdx=%d neighborIdx=%d", v[0], v[1]))
+				}
+			}
+		})
+		if err != nil {
+			c.Skipped = reason(err)
+			if member != nil {
+				member.Skipped = c.Skipped
+			}
+		}
+	}
+	return rep
+}
+
+func (r *IntegrityReport) checkMapping(m Mapping, base int) {
+	ids := make([]int, 0, len(m.Map))
+	for id := range m.Map {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	below := r.check(fmt.Sprintf("%s.%s menores que %d", m.Name, m.IdxColumn, base), SeverityError)
+	owners := make(map[int][]int, len(m.Map))
+	maxIdx := base - 1
+	for _, id := range ids {
+		idx := m.Map[id]
+		below.Checked++
+		if idx < base {
+			below.add(fmt.Sprintf("%s=%d %s=%d", m.IDColumn, id, m.IdxColumn, idx))
+			continue
+		}
+		owners[idx] = append(owners[idx], id)
+		if idx > maxIdx {
+			maxIdx = idx
+		}
+	}
+
+	// Índices compartidos: cada id extra sobre el mismo índice cuenta como un problema
+	shared := r.check(fmt.Sprintf("%s.%s compartidos por varios %s", m.Name, m.IdxColumn, m.IDColumn), SeverityError)
+	indices := make([]int, 0, len(owners))
+	for idx := range owners {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+	for _, idx := range indices {
+		shared.Checked++
+		if ids := owners[idx]; len(ids) > 1 {
+			shared.Orphans += len(ids) - 2
+			shared.add(fmt.Sprintf("%s=%d: %s %s", m.IdxColumn, idx, m.IDColumn, joinInts(ids)))
+		}
+	}
+
+	// Huecos: índices sin id en [base, max], reportados como rangos
+	gaps := r.check(fmt.Sprintf("%s.%s huecos en %d..%d", m.Name, m.IdxColumn, base, maxIdx), SeverityError)
+	gaps.Checked = maxIdx - base + 1
+	for idx := base; idx <= maxIdx; idx++ {
+		if _, ok := owners[idx]; ok {
+			continue
+		}
+		end := idx
+		for end+1 <= maxIdx {
+			if _, ok := owners[end+1]; ok {
+				break
+			}
+			end++
+		}
+		gaps.Orphans += end - idx
+		if end > idx {
+			gaps.add(fmt.Sprintf("%d-%d", idx, end))
+		} else {
+			gaps.add(fmt.Sprintf("%d", idx))
+		}
+		idx = end
+	}
+
+	datasetChecks := []string{
+		fmt.Sprintf("%s.%s -> dataset", m.Name, m.IDColumn),
+		fmt.Sprintf("dataset sin %s en %s", m.IdxColumn, m.Name),
+	}
+	switch {
+	case m.Skipped != "":
+		r.skip(m.Skipped, datasetChecks...)
+	case m.Dataset != nil:
+		absent := r.check(datasetChecks[0], SeverityError)
+		mapped := make(map[int]struct{}, len(ids))
+		for _, id := range ids {
+			absent.Checked++
+			mapped[id] = struct{}{}
+			if _, ok := m.Dataset[id]; !ok {
+				absent.add(fmt.Sprintf("%s=%d", m.IDColumn, id))
+			}
+		}
+		r.missing(datasetChecks[1], m.Dataset, mapped)
+	}
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%d", v)
+	}
+	return strings.Join(parts, ", ")
+}
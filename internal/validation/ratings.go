@@ -0,0 +1,304 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"pc4_etl/internal/models"
+)
+
+// Políticas ante ratings o timestamps fuera de dominio
+const (
+	RatingKeep    = "keep"    // solo contar
+	RatingDrop    = "drop"    // descartar el rating
+	RatingRescale = "rescale" // ajustar al step más cercano y recortar al rango (solo ratings)
+)
+
+// RatingScale es la escala permitida de ratings (Step 0 = continua)
+type RatingScale struct {
+	Min  float64
+	Max  float64
+	Step float64
+}
+
+// Escalas predefinidas
+var (
+	ScaleMovieLens = RatingScale{Min: 0.5, Max: 5.0, Step: 0.5} // ml-latest / ml-25m
+	ScaleFiveStar  = RatingScale{Min: 1, Max: 5, Step: 1}       // ml-100k / ml-1m
+)
+
+// String devuelve la escala en formato min:max:step
+func (s RatingScale) String() string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return f(s.Min) + ":" + f(s.Max) + ":" + f(s.Step)
+}
+
+// ParseRatingScale interpreta "movielens", "5-star" o "min:max[:step]"
+func ParseRatingScale(spec string) (RatingScale, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "movielens":
+		return ScaleMovieLens, nil
+	case "5-star", "ml-100k", "ml-1m":
+		return ScaleFiveStar, nil
+	}
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return RatingScale{}, fmt.Errorf("escala inválida %q (movielens, 5-star o min:max[:step])", spec)
+	}
+	vals := make([]float64, 3)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return RatingScale{}, fmt.Errorf("escala inválida %q: %v", spec, err)
+		}
+		vals[i] = v
+	}
+	scale := RatingScale{Min: vals[0], Max: vals[1], Step: vals[2]}
+	if scale.Max <= scale.Min || scale.Step < 0 {
+		return RatingScale{}, fmt.Errorf("escala inválida %q: se requiere min < max y step >= 0", spec)
+	}
+	return scale, nil
+}
+
+// ParseTimestamp interpreta segundos unix o una fecha YYYY-MM-DD (vacío = 0, sin límite)
+func ParseTimestamp(spec string) (int64, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, nil
+	}
+	if ts, err := strconv.ParseInt(spec, 10, 64); err == nil {
+		return ts, nil
+	}
+	t, err := time.Parse("2006-01-02", spec)
+	if err != nil {
+		return 0, fmt.Errorf("timestamp inválido %q (unix o YYYY-MM-DD)", spec)
+	}
+	return t.Unix(), nil
+}
+
+// RatingRules son las reglas de dominio de ratings y timestamps
+type RatingRules struct {
+	Scale           *RatingScale // nil = sin validar el rating
+	InputScale      *RatingScale // si se indica, los ratings se convierten linealmente de esta escala a Scale
+	RatingPolicy    string
+	MinTimestamp    int64 // 0 = sin límite
+	MaxTimestamp    int64 // 0 = sin límite
+	TimestampPolicy string
+	Now             time.Time // referencia para detectar fechas futuras
+}
+
+// RatingStats cuenta las violaciones de dominio encontradas
+type RatingStats struct {
+	Checked      int      `json:"checked"`
+	Invalid      int      `json:"invalid"`    // rating no numérico o no finito (siempre se descarta)
+	OutOfRange   int      `json:"outOfRange"` // fuera de [min, max]
+	OffStep      int      `json:"offStep"`    // no es múltiplo del step
+	Converted    int      `json:"converted"`  // convertidos desde --rating-input-scale
+	Rescaled     int      `json:"rescaled"`   // ajustados por la política rescale
+	TsMissing    int      `json:"tsMissing"`  // timestamp ausente o no numérico
+	TsBeforeMin  int      `json:"tsBeforeMin"`
+	TsAfterMax   int      `json:"tsAfterMax"`
+	TsFuture     int      `json:"tsFuture"`
+	Dropped      int      `json:"dropped"`
+	Samples      []string `json:"samples,omitempty"`
+	sampleLimit  int
+	ratingPolicy string
+	tsPolicy     string
+	scale        *RatingScale
+	inputScale   *RatingScale
+	minTs, maxTs int64
+	futureCutoff int64
+}
+
+// RatingValidator aplica RatingRules como models.RatingFilter y acumula RatingStats.
+// Se usa una instancia por pasada (LoadRatingStats y ProcessRatings) para no duplicar conteos.
+type RatingValidator struct {
+	RatingStats
+}
+
+// NewRatingValidator crea un validador con las reglas indicadas
+func NewRatingValidator(rules RatingRules, samples int) *RatingValidator {
+	now := rules.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	v := &RatingValidator{}
+	v.sampleLimit = samples
+	v.ratingPolicy = rules.RatingPolicy
+	v.tsPolicy = rules.TimestampPolicy
+	v.scale = rules.Scale
+	v.inputScale = rules.InputScale
+	v.minTs, v.maxTs = rules.MinTimestamp, rules.MaxTimestamp
+	// Tolerancia de un día para diferencias de zona horaria
+	v.futureCutoff = now.Add(24 * time.Hour).Unix()
+	return v
+}
+
+// Keep valida (y eventualmente corrige) el rating; NaN indica un rating no numérico. Los ratings
+// no numéricos o infinitos ("inf") se descartan con cualquier política: no tienen un valor que
+// conservar y no se pueden escribir en JSON
+func (v *RatingValidator) Keep(doc *models.RatingDoc) bool {
+	v.Checked++
+	drop := false
+
+	switch {
+	case math.IsNaN(doc.Rating) || math.IsInf(doc.Rating, 0):
+		v.Invalid++
+		v.sample("rating no numérico o infinito", doc)
+		drop = true
+	case v.scale != nil:
+		if v.inputScale != nil {
+			in, out := v.inputScale, v.scale
+			doc.Rating = out.Min + (doc.Rating-in.Min)*(out.Max-out.Min)/(in.Max-in.Min)
+			v.Converted++
+		}
+		if reason := v.checkScale(doc.Rating); reason != "" {
+			v.sample(reason, doc)
+			switch v.ratingPolicy {
+			case RatingDrop:
+				drop = true
+			case RatingRescale:
+				doc.Rating = v.fit(doc.Rating)
+				v.Rescaled++
+			}
+		}
+	}
+
+	tsViolation := ""
+	switch {
+	case doc.Timestamp <= 0:
+		v.TsMissing++
+		tsViolation = "timestamp ausente"
+	case v.minTs > 0 && doc.Timestamp < v.minTs:
+		v.TsBeforeMin++
+		tsViolation = "timestamp anterior al mínimo"
+	case v.maxTs > 0 && doc.Timestamp > v.maxTs:
+		v.TsAfterMax++
+		tsViolation = "timestamp posterior al máximo"
+	case doc.Timestamp > v.futureCutoff:
+		v.TsFuture++
+		tsViolation = "timestamp en el futuro"
+	}
+	if tsViolation != "" {
+		v.sample(tsViolation, doc)
+		if v.tsPolicy == RatingDrop {
+			drop = true
+		}
+	}
+
+	if drop {
+		v.Dropped++
+		return false
+	}
+	return true
+}
+
+// checkScale devuelve el motivo de la violación de escala ("" si es válido)
+func (v *RatingValidator) checkScale(r float64) string {
+	s := v.scale
+	const eps = 1e-9
+	if r < s.Min-eps || r > s.Max+eps {
+		v.OutOfRange++
+		return "rating fuera de rango"
+	}
+	if s.Step > 0 {
+		steps := (r - s.Min) / s.Step
+		if math.Abs(steps-math.Round(steps)) > 1e-6 {
+			v.OffStep++
+			return "rating fuera del step"
+		}
+	}
+	return ""
+}
+
+// fit recorta al rango y redondea al step más cercano
+func (v *RatingValidator) fit(r float64) float64 {
+	s := v.scale
+	r = math.Max(s.Min, math.Min(s.Max, r))
+	if s.Step > 0 {
+		r = s.Min + math.Round((r-s.Min)/s.Step)*s.Step
+		r = math.Round(r*1e6) / 1e6
+	}
+	return r
+}
+
+func (v *RatingValidator) sample(reason string, doc *models.RatingDoc) {
+	if len(v.Samples) < v.sampleLimit {
+		v.Samples = append(v.Samples, fmt.Sprintf("%s: userId=%d movieId=%d rating=%v timestamp=%d",
+			reason, doc.UserID, doc.MovieID, doc.Rating, doc.Timestamp))
+	}
+}
+
+// Violations devuelve el total de ratings con alguna violación de dominio
+func (v *RatingValidator) Violations() int {
+	return v.Invalid + v.OutOfRange + v.OffStep + v.TsMissing + v.TsBeforeMin + v.TsAfterMax + v.TsFuture
+}
+
+// Lines devuelve el resumen legible (consola y report.txt)
+func (v *RatingValidator) Lines() []string {
+	scale := "sin validar"
+	if v.scale != nil {
+		scale = v.scale.String()
+	}
+	lines := []string{
+		fmt.Sprintf("  • Escala: %s (política rating: %s, timestamp: %s)", scale, v.ratingPolicy, v.tsPolicy),
+		fmt.Sprintf("  • Ratings revisados: %d", v.Checked),
+	}
+	if v.Converted > 0 {
+		lines = append(lines, fmt.Sprintf("  • Convertidos desde la escala de entrada %s: %d", v.inputScale, v.Converted))
+	}
+	counts := []struct {
+		label string
+		n     int
+	}{
+		{"Ratings no numéricos o infinitos (descartados)", v.Invalid},
+		{"Ratings fuera de rango", v.OutOfRange},
+		{"Ratings fuera del step", v.OffStep},
+		{"Timestamps ausentes", v.TsMissing},
+		{"Timestamps anteriores al mínimo", v.TsBeforeMin},
+		{"Timestamps posteriores al máximo", v.TsAfterMax},
+		{"Timestamps en el futuro", v.TsFuture},
+		{"Ratings reescalados", v.Rescaled},
+		{"Ratings descartados", v.Dropped},
+	}
+	for _, c := range counts {
+		if c.n > 0 {
+			lines = append(lines, fmt.Sprintf("  ⚠ %s: %d", c.label, c.n))
+		}
+	}
+	if v.Violations() == 0 {
+		lines = append(lines, "  ✓ Sin violaciones de dominio")
+	}
+	for _, s := range v.Samples {
+		lines = append(lines, "    ej: "+s)
+	}
+	return lines
+}
+
+// ChainFilters combina filtros en orden (los nil se omiten); nil si no queda ninguno
+func ChainFilters(filters ...models.RatingFilter) models.RatingFilter {
+	var chain filterChain
+	for _, f := range filters {
+		if f != nil {
+			chain = append(chain, f)
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain
+}
+
+type filterChain []models.RatingFilter
+
+func (c filterChain) Keep(doc *models.RatingDoc) bool {
+	for _, f := range c {
+		if !f.Keep(doc) {
+			return false
+		}
+	}
+	return true
+}
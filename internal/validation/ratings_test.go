@@ -0,0 +1,23 @@
+package validation
+
+import (
+	"math"
+	"testing"
+
+	"pc4_etl/internal/models"
+)
+
+// Los ratings no numéricos o infinitos se descartan con cualquier política
+func TestRatingValidatorDropsNonFinite(t *testing.T) {
+	for _, policy := range []string{RatingKeep, RatingDrop, RatingRescale} {
+		v := NewRatingValidator(RatingRules{Scale: &ScaleMovieLens, RatingPolicy: policy, TimestampPolicy: RatingKeep}, 5)
+		for _, r := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+			if v.Keep(&models.RatingDoc{UserID: 1, MovieID: 1, Rating: r, Timestamp: 1}) {
+				t.Errorf("política %s: rating %v conservado", policy, r)
+			}
+		}
+		if v.Invalid != 3 || v.OutOfRange != 0 {
+			t.Errorf("política %s: Invalid=%d OutOfRange=%d, want 3 y 0", policy, v.Invalid, v.OutOfRange)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"pc4_etl/internal/loaders"
+	"pc4_etl/internal/models"
+	"pc4_etl/internal/utils"
+)
+
+// SchemaOptions configura la validación de las salidas contra el esquema y sus invariantes
+type SchemaOptions struct {
+	Scale   *RatingScale // escala válida de ratings (nil = no validar)
+	ItemMap string       // item_map.csv para verificar iIdx ("" o ausente = omitir)
+	UserMap string       // user_map.csv para verificar uIdx ("" o ausente = omitir)
+}
+
+// CheckSchema recorre cada NDJSON de outDir, lo decodifica en los tipos de models y verifica
+// campos requeridos (los del validador $jsonSchema de setup.js), tipos, unicidad de claves e
+// invariantes (k == len(neighbors), ratings dentro de la escala, iIdx/uIdx según los mapeos)
+func CheckSchema(outDir string, opts SchemaOptions, samples int) *IntegrityReport {
+	rep := newReport("schema", samples)
+	path := func(name string) string { return filepath.Join(outDir, name) }
+
+	// Mapeos para verificar iIdx/uIdx (si no se pueden cargar, esos chequeos se omiten)
+	var itemMap, userMap map[int]int
+	itemMapSkip, userMapSkip := "sin item_map.csv", "sin user_map.csv"
+	if opts.ItemMap != "" {
+		m, err := loaders.LoadItemMap(opts.ItemMap)
+		if err == nil {
+			itemMap = m
+		} else {
+			itemMapSkip = reason(err)
+		}
+	}
+	if opts.UserMap != "" {
+		m, err := loaders.LoadUserMap(opts.UserMap)
+		if err == nil {
+			userMap = m
+		} else {
+			userMapSkip = reason(err)
+		}
+	}
+
+	// movies
+	if c := rep.collection(path("movies.ndjson"), "movies", false); c != nil {
+		movieIdx := rep.check("movies.iIdx = item_map", SeverityError)
+		if itemMap == nil {
+			movieIdx.Skipped = itemMapSkip
+		}
+		c.scan(func(line []byte) error {
+			var doc models.MovieDoc
+			if err := json.Unmarshal(line, &doc); err != nil {
+				return err
+			}
+			c.uniqueID("movieId", doc.MovieID)
+			c.positive("movieId", doc.MovieID)
+			if itemMap != nil {
+				movieIdx.Checked++
+				if want, ok := itemMap[doc.MovieID]; !ok {
+					movieIdx.add(fmt.Sprintf("movieId=%d iIdx=%s sin entrada en item_map", doc.MovieID, intPtr(doc.IIdx)))
+				} else if doc.IIdx == nil || *doc.IIdx != want {
+					movieIdx.add(fmt.Sprintf("movieId=%d iIdx=%s item_map=%d", doc.MovieID, intPtr(doc.IIdx), want))
+				}
+			}
+			return nil
+		})
+	}
+
+	// ratings
+	if c := rep.collection(path("ratings.ndjson"), "ratings", false); c != nil {
+		scale := rep.check("ratings.rating dentro de la escala", SeverityError)
+		if opts.Scale == nil {
+			scale.Skipped = "escala no indicada"
+		}
+		validator := RatingValidator{}
+		validator.scale = opts.Scale
+		c.scan(func(line []byte) error {
+			var doc models.RatingDoc
+			if err := json.Unmarshal(line, &doc); err != nil {
+				return err
+			}
+			c.uniquePair("userId+movieId", doc.UserID, doc.MovieID)
+			c.positive("userId", doc.UserID)
+			c.positive("movieId", doc.MovieID)
+			if opts.Scale != nil {
+				scale.Checked++
+				if why := validator.checkScale(doc.Rating); why != "" {
+					scale.add(fmt.Sprintf("%s: userId=%d movieId=%d rating=%v", why, doc.UserID, doc.MovieID, doc.Rating))
+				}
+			}
+			return nil
+		})
+	}
+
+	// users
+	if c := rep.collection(path("users.ndjson"), "users", false); c != nil {
+		userIdx := rep.check("users.uIdx = user_map", SeverityError)
+		if userMap == nil {
+			userIdx.Skipped = userMapSkip
+		}
+		c.scan(func(line []byte) error {
+			var doc models.UserDoc
+			if err := json.Unmarshal(line, &doc); err != nil {
+				return err
+			}
+			c.uniqueID("userId", doc.UserID)
+			c.unique("email", doc.Email)
+			c.unique("username", doc.Username)
+			c.positive("userId", doc.UserID)
+			if userMap != nil {
+				userIdx.Checked++
+				if want, ok := userMap[doc.UserID]; !ok {
+					userIdx.add(fmt.Sprintf("userId=%d uIdx=%s sin entrada en user_map", doc.UserID, intPtr(doc.UIdx)))
+				} else if doc.UIdx == nil || *doc.UIdx != want {
+					userIdx.add(fmt.Sprintf("userId=%d uIdx=%s user_map=%d", doc.UserID, intPtr(doc.UIdx), want))
+				}
+			}
+			return nil
+		})
+	}
+
+	// similarities
+	if c := rep.collection(path("similarities.ndjson"), "similarities", false); c != nil {
+		k := rep.check("similarities.k = len(neighbors)", SeverityError)
+		simIdx := rep.check("similarities.iIdx = item_map", SeverityError)
+		if itemMap == nil {
+			simIdx.Skipped = itemMapSkip
+		}
+		c.scan(func(line []byte) error {
+			var doc models.SimilarityDoc
+			if err := json.Unmarshal(line, &doc); err != nil {
+				return err
+			}
+			c.unique("_id", doc.ID)
+			c.positive("movieId", doc.MovieID)
+			k.Checked++
+			if doc.K != len(doc.Neighbors) {
+				k.add(fmt.Sprintf("_id=%s k=%d neighbors=%d", doc.ID, doc.K, len(doc.Neighbors)))
+			}
+			if itemMap != nil {
+				simIdx.Checked++
+				if want, ok := itemMap[doc.MovieID]; !ok {
+					simIdx.add(fmt.Sprintf("_id=%s movieId=%d iIdx=%d sin entrada en item_map", doc.ID, doc.MovieID, doc.IIdx))
+				} else if doc.IIdx != want {
+					simIdx.add(fmt.Sprintf("_id=%s movieId=%d iIdx=%d item_map=%d", doc.ID, doc.MovieID, doc.IIdx, want))
+				}
+				for _, n := range doc.Neighbors {
+					if want, ok := itemMap[n.MovieID]; !ok {
+						simIdx.add(fmt.Sprintf("_id=%s vecino movieId=%d iIdx=%d sin entrada en item_map", doc.ID, n.MovieID, n.IIdx))
+					} else if n.IIdx != want {
+						simIdx.add(fmt.Sprintf("_id=%s vecino movieId=%d iIdx=%d item_map=%d", doc.ID, n.MovieID, n.IIdx, want))
+					}
+				}
+			}
+			return nil
+		})
+	}
+
+	// user_ratings (opcional, --user-buckets)
+	if c := rep.collection(path("user_ratings.ndjson"), "user_ratings", true); c != nil {
+		count := rep.check("user_ratings.count = len(ratings)", SeverityError)
+		c.scan(func(line []byte) error {
+			var doc models.UserRatingBucket
+			if err := json.Unmarshal(line, &doc); err != nil {
+				return err
+			}
+			c.unique("_id", doc.ID)
+			c.uniquePair("userId+bucket", doc.UserID, doc.Bucket)
+			count.Checked++
+			if doc.Count != len(doc.Ratings) {
+				count.add(fmt.Sprintf("_id=%s count=%d ratings=%d", doc.ID, doc.Count, len(doc.Ratings)))
+			}
+			return nil
+		})
+	}
+	return rep
+}
+
+// collectionChecks son los chequeos por documento de una colección
+type collectionChecks struct {
+	rep      *IntegrityReport
+	path     string
+	name     string
+	decode   *Check
+	required *Check
+	ids      *Check
+	uniques  map[string]*Check
+	seen     map[string]map[string]int // campo -> valor -> línea
+	seenIDs  map[string]map[uint64]int // campo -> id o par de ids empaquetado -> línea
+	lineNo   int
+}
+
+// collection registra los chequeos de decodificación, campos requeridos e ids positivos de un
+// NDJSON; devuelve nil si el archivo no existe (si es optional no se registra como omitido)
+func (r *IntegrityReport) collection(path, name string, optional bool) *collectionChecks {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if !optional {
+			r.skip(reason(err), name+": JSON y tipos")
+		}
+		return nil
+	}
+	return &collectionChecks{
+		rep:      r,
+		path:     path,
+		name:     name,
+		decode:   r.check(name+": JSON y tipos", SeverityError),
+		required: r.check(name+": campos requeridos", SeverityError),
+		ids:      r.check(name+": ids positivos", SeverityError),
+		uniques:  make(map[string]*Check),
+		seen:     make(map[string]map[string]int),
+		seenIDs:  make(map[string]map[uint64]int),
+	}
+}
+
+// scan recorre el NDJSON verificando JSON y campos requeridos (los del validador de setup.js);
+// fn decodifica el documento en su tipo y registra claves e invariantes
+func (c *collectionChecks) scan(fn func(line []byte) error) {
+	required := utils.RequiredFields(c.name)
+	err := loaders.ReadNDJSON(c.path, func(line []byte) error {
+		c.lineNo++
+		c.decode.Checked++
+		c.required.Checked++
+		c.ids.Checked++
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(line, &fields); err != nil {
+			c.decode.add(fmt.Sprintf("línea %d: %v", c.lineNo, err))
+			return nil
+		}
+		for _, f := range required {
+			if v, ok := fields[f]; !ok || string(v) == "null" {
+				c.required.add(fmt.Sprintf("línea %d: falta %s", c.lineNo, f))
+			}
+		}
+		if err := fn(line); err != nil {
+			c.decode.add(fmt.Sprintf("línea %d: %v", c.lineNo, err))
+		}
+		return nil
+	})
+	if err != nil {
+		c.decode.add(reason(err))
+	}
+}
+
+// unique verifica que el valor del campo no se haya visto antes en la colección
+func (c *collectionChecks) unique(field, value string) {
+	check := c.uniqueCheck(field)
+	if c.seen[field] == nil {
+		c.seen[field] = make(map[string]int)
+	}
+	check.Checked++
+	if first, dup := c.seen[field][value]; dup {
+		check.add(fmt.Sprintf("%s=%q en líneas %d y %d", field, value, first, c.lineNo))
+		return
+	}
+	c.seen[field][value] = c.lineNo
+}
+
+// uniqueID verifica la unicidad de un id entero sin guardar una cadena por documento
+func (c *collectionChecks) uniqueID(field string, id int) {
+	if !packable(id) {
+		c.unique(field, strconv.Itoa(id))
+		return
+	}
+	if first, dup := c.uniqueKey(field, uint64(id)); dup {
+		c.uniques[field].add(fmt.Sprintf("%s=%d en líneas %d y %d", field, id, first, c.lineNo))
+	}
+}
+
+// uniquePair verifica la unicidad de un par de ids (ej: userId+movieId) empaquetado en un
+// uint64, como rowKey en duplicates.go; los ids fuera de 0..2^32-1 usan la clave de texto
+func (c *collectionChecks) uniquePair(field string, a, b int) {
+	if !packable(a) || !packable(b) {
+		c.unique(field, fmt.Sprintf("%d/%d", a, b))
+		return
+	}
+	if first, dup := c.uniqueKey(field, uint64(a)<<32|uint64(b)); dup {
+		c.uniques[field].add(fmt.Sprintf("%s=\"%d/%d\" en líneas %d y %d", field, a, b, first, c.lineNo))
+	}
+}
+
+// uniqueKey registra una clave entera y devuelve la línea de su primera aparición si ya se vio
+func (c *collectionChecks) uniqueKey(field string, key uint64) (int, bool) {
+	check := c.uniqueCheck(field)
+	seen := c.seenIDs[field]
+	if seen == nil {
+		seen = make(map[uint64]int)
+		c.seenIDs[field] = seen
+	}
+	check.Checked++
+	if first, dup := seen[key]; dup {
+		return first, true
+	}
+	seen[key] = c.lineNo
+	return 0, false
+}
+
+// uniqueCheck devuelve (creándolo si hace falta) el chequeo de unicidad del campo
+func (c *collectionChecks) uniqueCheck(field string) *Check {
+	check := c.uniques[field]
+	if check == nil {
+		check = c.rep.check(fmt.Sprintf("%s.%s único", c.name, field), SeverityError)
+		c.uniques[field] = check
+	}
+	return check
+}
+
+// packable indica si un id entra en 32 bits sin signo (ver uniquePair)
+func packable(id int) bool {
+	return id >= 0 && int64(id) <= math.MaxUint32
+}
+
+// positive verifica que un id sea mayor que cero
+func (c *collectionChecks) positive(field string, v int) {
+	if v <= 0 {
+		c.ids.add(fmt.Sprintf("línea %d: %s=%d", c.lineNo, field, v))
+	}
+}
+
+func intPtr(v *int) string {
+	if v == nil {
+		return "null"
+	}
+	return strconv.Itoa(*v)
+}
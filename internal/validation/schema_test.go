@@ -0,0 +1,42 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSchemaKeysAndMaps(t *testing.T) {
+	dir := t.TempDir()
+	write := func(file, content string) string {
+		path := filepath.Join(dir, file)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("movies.ndjson", `{"movieId":1,"iIdx":0,"title":"A"}
+{"movieId":2,"iIdx":1,"title":"B"}
+`)
+	write("ratings.ndjson", `{"userId":1,"movieId":1,"rating":4,"timestamp":1}
+{"userId":1,"movieId":2,"rating":4,"timestamp":1}
+{"userId":2,"movieId":1,"rating":4,"timestamp":1}
+{"userId":1,"movieId":2,"rating":3,"timestamp":2}
+`)
+	opts := SchemaOptions{
+		ItemMap: write("item_map.csv", "movieId,iIdx\n1,0\n"),
+	}
+
+	rep := CheckSchema(dir, opts, 5)
+	orphans := make(map[string]int)
+	for _, c := range rep.Checks {
+		orphans[c.Name] = c.Orphans
+	}
+	if got := orphans["ratings.userId+movieId único"]; got != 1 {
+		t.Errorf("ratings.userId+movieId único = %d, want 1\n%v", got, rep.Lines())
+	}
+	// movieId 2 no está en item_map: debe reportarse aunque su iIdx parezca válido
+	if got := orphans["movies.iIdx = item_map"]; got != 1 {
+		t.Errorf("movies.iIdx = item_map = %d, want 1\n%v", got, rep.Lines())
+	}
+}
@@ -9,16 +9,223 @@ import (
 	"time"
 
 	"pc4_etl/internal/external"
+	"pc4_etl/internal/inputs"
 	"pc4_etl/internal/loaders"
 	"pc4_etl/internal/mappers"
 	"pc4_etl/internal/models"
 	"pc4_etl/internal/processors"
+	"pc4_etl/internal/sources"
+	"pc4_etl/internal/taxonomy"
 	"pc4_etl/internal/utils"
+	"pc4_etl/internal/validation"
 )
 
-var yearRe = regexp.MustCompile(`\((\d{4})\)\s*$`)
+var yearRe = regexp.MustCompile(`\((\d{4})\s*([-–]\s*(\d{4})?)?\)\s*$`)
+
+// sourceFlags agrupa los flags de los formatos externos (generic-csv, jsonl)
+type sourceFlags struct {
+	userField, itemField, ratingField, timeField *string
+	titleField, genresField, genresSep           *string
+	delimiter                                    *string
+	itemKeysDir                                  *string
+}
+
+// registerInputFlags registra los flags de codificación y delimitador de las entradas
+func registerInputFlags(fs *flag.FlagSet) {
+	fs.Func("input-encoding", "Codificación de las entradas: auto (UTF-8, bytes inválidos como Windows-1252), utf-8, latin-1 o windows-1252", inputs.SetEncoding)
+	fs.Func("csv-delimiter", "Separador de los CSV de entrada: auto (detectar en la cabecera), \",\", \";\", \"|\" o tab", inputs.SetDefaultDelimiter)
+}
+
+// inputsReportSection describe cómo se leyó cada archivo de entrada (codificación, BOM y delimitador)
+func inputsReportSection() utils.ReportSection {
+	section := utils.ReportSection{Title: "ARCHIVOS DE ENTRADA"}
+	for _, d := range inputs.Detections() {
+		line := fmt.Sprintf("  • %s: %s", d.Path, d.Encoding)
+		if d.BOM {
+			line += " (BOM)"
+		}
+		if d.Delimiter != "" {
+			line += fmt.Sprintf(", delimitador %q", d.Delimiter)
+		}
+		section.Lines = append(section.Lines, line)
+	}
+	return section
+}
+
+// registerSourceFlags registra los flags de fuentes externas en el FlagSet indicado
+func registerSourceFlags(fs *flag.FlagSet) *sourceFlags {
+	return &sourceFlags{
+		userField:   fs.String("source-user", "", "generic-csv/jsonl: columna o clave JSON del usuario (default: userId | reviewerID)"),
+		itemField:   fs.String("source-item", "", "generic-csv/jsonl: columna o clave JSON del item (default: movieId | asin)"),
+		ratingField: fs.String("source-rating", "", "generic-csv/jsonl: columna o clave JSON del rating (default: rating | overall)"),
+		timeField:   fs.String("source-time", "", "generic-csv/jsonl: columna o clave JSON del timestamp, unix o fecha (default: timestamp | unixReviewTime)"),
+		titleField:  fs.String("source-title", "", "generic-csv/jsonl: columna o clave JSON del título en el archivo de items (default: title)"),
+		genresField: fs.String("source-genres", "", "generic-csv/jsonl: columna o clave JSON de géneros/categorías (default: genres | categories)"),
+		genresSep:   fs.String("source-genres-sep", "|", "generic-csv: separador de géneros dentro de la columna"),
+		delimiter:   fs.String("source-delimiter", "auto", "generic-csv: separador de campos (auto = detectar; ej: \";\" para Book-Crossing)"),
+		itemKeysDir: fs.String("keys-dir", "", "Directorio de item_keys.csv/user_keys.csv (mapeo clave string -> id; default: --data-dir)"),
+	}
+}
+
+// config construye la configuración de la fuente a partir de los flags
+func (f *sourceFlags) config(format, dataDir, itemsPath, ratingsPath string) sources.Config {
+	var delimiter rune // 0 = detectar
+	switch *f.delimiter {
+	case "", "auto":
+	case "\\t", "tab":
+		delimiter = '\t'
+	default:
+		delimiter = []rune(*f.delimiter)[0]
+	}
+	keysDir := *f.itemKeysDir
+	if keysDir == "" {
+		keysDir = dataDir
+	}
+	return sources.Config{
+		Format:      format,
+		RatingsPath: ratingsPath,
+		ItemsPath:   itemsPath,
+		Delimiter:   delimiter,
+		GenresSep:   *f.genresSep,
+		UserField:   *f.userField,
+		ItemField:   *f.itemField,
+		RatingField: *f.ratingField,
+		TimeField:   *f.timeField,
+		TitleField:  *f.titleField,
+		GenresField: *f.genresField,
+		KeysDir:     keysDir,
+	}
+}
+
+// exitCleanups son las limpiezas pendientes (directorios temporales, copia de stdin); os.Exit no
+// ejecuta los defer, así que los errores fatales terminan con exit en lugar de os.Exit
+var exitCleanups []func()
+
+// atExit registra una limpieza que se ejecuta al terminar, con o sin error
+func atExit(fn func()) {
+	exitCleanups = append(exitCleanups, fn)
+}
+
+// runCleanups ejecuta las limpiezas registradas en orden inverso (una sola vez)
+func runCleanups() {
+	for i := len(exitCleanups) - 1; i >= 0; i-- {
+		exitCleanups[i]()
+	}
+	exitCleanups = nil
+}
+
+// exit ejecuta las limpiezas registradas y termina con el código indicado
+func exit(code int) {
+	runCleanups()
+	os.Exit(code)
+}
+
+// datasetInputs son las rutas de movies/ratings resueltas según el formato del dataset
+type datasetInputs struct {
+	Format  string
+	Movies  string
+	Ratings string
+	Users   string               // demografía convertida (ml-100k/ml-1m); vacío si no hay
+	Source  *sources.Result      // generic-csv / jsonl
+	Export  *loaders.ExportState // ndjson (salida previa del ETL o mongoexport)
+	cleanup func()
+}
+
+// Cleanup elimina el directorio temporal de conversión (si lo hubo)
+func (d *datasetInputs) Cleanup() {
+	if d.cleanup != nil {
+		d.cleanup()
+	}
+}
+
+// resolveDatasetInputs devuelve las rutas de movies/ratings a usar según el formato del dataset.
+// Para ml-100k y ml-1m convierte los archivos nativos a CSV en un directorio temporal; para
+// generic-csv y jsonl materializa las fuentes externas (claves string mapeadas a IDs enteros);
+// para ndjson reconstruye el estado desde una exportación previa.
+func resolveDatasetInputs(format, dataDir, moviesPath, ratingsPath string, src *sourceFlags) (*datasetInputs, error) {
+	if format == loaders.FormatAuto {
+		format = loaders.DetectDatasetFormat(dataDir)
+	}
+	in := &datasetInputs{Format: format, Movies: moviesPath, Ratings: ratingsPath}
+	switch format {
+	case loaders.FormatCSV:
+		return in, nil
+	case loaders.FormatML100K, loaders.FormatML1M, loaders.FormatNDJSON, sources.FormatGenericCSV, sources.FormatJSONL:
+	default:
+		return nil, fmt.Errorf("formato de dataset desconocido: %s", format)
+	}
+
+	stagingDir, err := os.MkdirTemp("", "etl-"+format+"-")
+	if err != nil {
+		return nil, err
+	}
+	in.cleanup = func() { os.RemoveAll(stagingDir) }
+
+	var files *loaders.DatasetFiles
+	switch {
+	case sources.IsSourceFormat(format):
+		in.Source, err = sources.Materialize(src.config(format, dataDir, moviesPath, ratingsPath), stagingDir)
+		if in.Source != nil {
+			files = in.Source.Files
+		}
+	case format == loaders.FormatNDJSON:
+		in.Export, files, err = loaders.LoadExport(dataDir, stagingDir)
+	default:
+		files, err = loaders.ConvertDataset(format, dataDir, stagingDir)
+	}
+	if err != nil {
+		in.Cleanup()
+		return nil, err
+	}
+	in.Movies, in.Ratings, in.Users = files.Movies, files.Ratings, files.Users
+	return in, nil
+}
+
+// reportSourceKeys informa la materialización de una fuente externa y persiste el mapeo de claves:
+// siempre en outDir y, con --update-mappings, también en el directorio de claves
+func reportSourceKeys(res *sources.Result, outDir, keysDir string, updateMappings bool) {
+	fmt.Printf("✓ Fuente %s: %d ratings (%d descartados), %d items, %d usuarios\n",
+		res.Files.Format, res.Ratings, res.Skipped, res.Items, res.UserKeys.Count())
+	if !res.ItemsLoaded {
+		fmt.Println("  ⚠ Sin archivo de items: se usan las claves de item como título")
+	}
+	if err := res.SaveKeys(outDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Advertencia: no se pudo guardar item_keys.csv/user_keys.csv: %v\n", err)
+	}
+	changed := res.ItemKeys.HasChanged() || res.UserKeys.HasChanged()
+	switch {
+	case changed && updateMappings:
+		if err := res.SaveKeys(keysDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo actualizar item_keys.csv/user_keys.csv: %v\n", err)
+		} else {
+			fmt.Printf("  ✓ item_keys.csv y user_keys.csv actualizados en %s\n", keysDir)
+		}
+	case changed:
+		fmt.Println("  ⚠ Se asignaron IDs nuevos a claves; use --update-mappings para persistir item_keys.csv/user_keys.csv")
+	}
+}
 
 func main() {
+	defer runCleanups()
+
+	// Subcomandos (el ETL completo se ejecuta sin subcomando)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "split":
+			runSplit(os.Args[2:])
+			return
+		case "validate":
+			runValidate(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "mappings":
+			runMappings(os.Args[2:])
+			return
+		}
+	}
+
 	startTime := time.Now()
 
 	// Intentar cargar .env antes de parsear flags
@@ -27,6 +234,11 @@ func main() {
 	}
 
 	dataDir := flag.String("data-dir", "data", "Directorio con los csv (default: data)")
+	datasetFormat := flag.String("dataset-format", loaders.FormatAuto, "Formato del dataset: auto, csv (ml-latest/ml-25m), ml-100k, ml-1m, generic-csv, jsonl o ndjson (exportación previa)")
+	var columnOverrides inputs.ColumnOverrides
+	flag.Var(&columnOverrides, "column", "Override de columna por cabecera, repetible (ej: --column ratings.movieId=item_id)")
+	srcFlags := registerSourceFlags(flag.CommandLine)
+	registerInputFlags(flag.CommandLine)
 	moviesFile := flag.String("movies-file", "movies.csv", "Nombre de movies.csv")
 	ratingsFile := flag.String("ratings-file", "ratings.csv", "Nombre de ratings.csv")
 	linksFile := flag.String("links-file", "links.csv", "Nombre de links.csv")
@@ -36,11 +248,14 @@ func main() {
 	itemMapFile := flag.String("item-map-file", "item_map.csv", "Nombre de item_map.csv")
 	userMapFile := flag.String("user-map-file", "user_map.csv", "Nombre de user_map.csv")
 	similaritiesFile := flag.String("similarities-file", "item_topk_cosine_conc.csv", "Nombre de item_topk_cosine_conc.csv")
+	mappingsDir := flag.String("mappings-dir", "", "Directorio de item_map.csv, user_map.csv y similitudes (vacío = --data-dir, o el directorio del .zip)")
 	outDir := flag.String("out-dir", "out", "Directorio de salida para NDJSON")
+	dbName := flag.String("db-name", "movielens", "Nombre de la base de datos MongoDB usada en los scripts de importación")
 	minRelevance := flag.Float64("min-relevance", 0.5, "Relevancia mínima para genome tags (0.0-1.0)")
 	topGenomeTags := flag.Int("top-genome-tags", 10, "Número máximo de genome tags por película")
 	hashPasswords := flag.Bool("hash-passwords", true, "Hashear passwords con bcrypt (más lento pero seguro)")
 	updateMappings := flag.Bool("update-mappings", false, "Actualizar archivos item_map.csv y user_map.csv con nuevos IDs encontrados")
+	indexBase := flag.Int("index-base", 0, "Primer índice de iIdx/uIdx en mapeos, similitudes y salidas: 0 o 1")
 
 	// TMDB API flags
 	tmdbAPIKey := flag.String("tmdb-api-key", "", "TMDB API Key (opcional, se lee de .env si no se especifica)")
@@ -53,7 +268,43 @@ func main() {
 	processUsers := flag.Bool("process-users", true, "Si es true, genera users.ndjson")
 	processSimilarities := flag.Bool("process-similarities", true, "Si es true, genera similarities.ndjson")
 
+	// Salidas opcionales derivadas
+	userBuckets := flag.Bool("user-buckets", false, "Si es true, genera user_ratings.ndjson (ratings agrupados por usuario, requiere --process-ratings)")
+	bucketSize := flag.Int("bucket-size", 500, "Número máximo de ratings por bucket en user_ratings.ndjson")
+	exportMatrix := flag.Bool("export-matrix", false, "Si es true, exporta la matriz de ratings (MatrixMarket y CSR binario) en out/matrix (requiere --process-ratings)")
+	exportGraph := flag.Bool("export-graph", false, "Si es true, genera CSVs de nodos/relaciones para neo4j-admin import en out/graph")
+	jsonldMode := flag.String("jsonld", "", "Genera schema.org Movie JSON-LD: \"ndjson\" (movies.jsonld.ndjson) o \"files\" (out/jsonld/<movieId>.jsonld); vacío = desactivado")
+	embedSimilar := flag.Int("embed-similar", 0, "Número de películas similares a embeber en cada movie (0 = desactivado)")
+
+	// Integridad referencial
+	checkIntegrity := flag.Bool("check-integrity", false, "Si es true, verifica la integridad referencial de las entradas y la reporta (integrity.json)")
+	orphansPolicy := flag.String("orphans", validation.PolicyKeep, "Política ante referencias huérfanas: keep (reportar y conservar), drop (descartar ratings de películas inexistentes y similitudes sin item_map) o fail (abortar); drop/fail activan --check-integrity")
+
+	// User tags
+	tagSynonymsFile := flag.String("tag-synonyms", "", "CSV de sinónimos de user tags (tag,canonical) que se suma a los incluidos (relativo a --data-dir)")
+	tagBlocklistFile := flag.String("tag-blocklist", "", "Archivo con términos bloqueados en user tags, uno por línea (relativo a --data-dir)")
+	tagFuzzyDistance := flag.Int("tag-fuzzy-distance", 0, "Distancia de edición máxima para unir typos en user tags de 8 o más caracteres (0 = desactivado)")
+
+	// Géneros
+	genreTaxonomyFile := flag.String("genre-taxonomy", "", "CSV de taxonomía de géneros (raw,id,parent,name_es,name_en) que extiende la incluida para MovieLens")
+	genresFormat := flag.String("genres-format", taxonomy.FormatRaw, "Formato de genres en movies/users: raw (como en movies.csv), canonical (ids de la taxonomía) u objects (objetos {id, parent, names} en movies)")
+
+	// Perfil del dataset
+	profileDataset := flag.Bool("profile", false, "Si es true, calcula el perfil del dataset (distribuciones, cuantiles, densidad, cola larga, cobertura) en profile.json y report.txt")
+
+	// Duplicados
+	duplicatesPolicy := flag.String("duplicates", "", "Detecta claves repetidas (movies, ratings, links, genome) y las resuelve: keep-first, keep-last, keep-latest-timestamp o fail; vacío = desactivado")
+
+	// Dominio de ratings y timestamps
+	ratingScale := flag.String("rating-scale", "auto", "Escala válida de ratings: auto (según el formato del dataset), movielens (0.5-5.0 en pasos de 0.5), 5-star (1-5 enteros), min:max[:step] o none")
+	ratingInputScale := flag.String("rating-input-scale", "", "Escala de los ratings de entrada (min:max); si se indica se convierten linealmente a --rating-scale (ej: 1:10)")
+	ratingPolicy := flag.String("rating-policy", validation.RatingKeep, "Política ante ratings fuera de la escala: keep (reportar), drop (descartar) o rescale (ajustar al rango y step)")
+	timestampMin := flag.String("timestamp-min", "", "Timestamp mínimo válido (unix o YYYY-MM-DD; vacío = sin límite)")
+	timestampMax := flag.String("timestamp-max", "", "Timestamp máximo válido (unix o YYYY-MM-DD; vacío = sin límite; siempre se reportan fechas futuras)")
+	timestampPolicy := flag.String("timestamp-policy", validation.RatingKeep, "Política ante timestamps inválidos: keep (reportar) o drop (descartar)")
+
 	flag.Parse()
+	atExit(inputs.Cleanup)
 
 	// Si no se especificó API key por flag, intentar leerla de variable de entorno
 	if *tmdbAPIKey == "" {
@@ -63,21 +314,39 @@ func main() {
 	os.MkdirAll(*outDir, 0o755)
 
 	// Rutas de archivos
-	moviesPath := filepath.Join(*dataDir, *moviesFile)
-	ratingsPath := filepath.Join(*dataDir, *ratingsFile)
-	linksPath := filepath.Join(*dataDir, *linksFile)
-	tagsPath := filepath.Join(*dataDir, *tagsFile)
-	genomeTagsPath := filepath.Join(*dataDir, *genomeTagsFile)
-	genomeScoresPath := filepath.Join(*dataDir, *genomeScoresFile)
-	itemMapPath := filepath.Join(*dataDir, *itemMapFile)
-	userMapPath := filepath.Join(*dataDir, *userMapFile)
-	similaritiesPath := filepath.Join(*dataDir, *similaritiesFile)
+	moviesPath := inputs.Resolve(*dataDir, *moviesFile)
+	ratingsPath := inputs.Resolve(*dataDir, *ratingsFile)
+	linksPath := inputs.Resolve(*dataDir, *linksFile)
+	tagsPath := inputs.Resolve(*dataDir, *tagsFile)
+	genomeTagsPath := inputs.Resolve(*dataDir, *genomeTagsFile)
+	genomeScoresPath := inputs.Resolve(*dataDir, *genomeScoresFile)
+	mapsDir := inputs.MappingsDir(*dataDir, *mappingsDir)
+	itemMapPath := inputs.Resolve(mapsDir, *itemMapFile)
+	userMapPath := inputs.Resolve(mapsDir, *userMapFile)
+	similaritiesPath := inputs.Resolve(mapsDir, *similaritiesFile)
+
+	// Formatos nativos (ml-100k, ml-1m): convertir a CSV estándar antes de procesar
+	dataset, err := resolveDatasetInputs(*datasetFormat, *dataDir, moviesPath, ratingsPath, srcFlags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error preparando dataset:", err)
+		exit(1)
+	}
+	atExit(dataset.Cleanup)
+	moviesPath, ratingsPath = dataset.Movies, dataset.Ratings
+	export := dataset.Export
 
 	moviesOut := filepath.Join(*outDir, "movies.ndjson")
 	ratingsOut := filepath.Join(*outDir, "ratings.ndjson")
 	usersOut := filepath.Join(*outDir, "users.ndjson")
 	similaritiesOut := filepath.Join(*outDir, "similarities.ndjson")
 	passwordLogOut := filepath.Join(*outDir, "passwords_log.csv")
+	userRatingsOut := filepath.Join(*outDir, "user_ratings.ndjson")
+	matrixOutDir := filepath.Join(*outDir, "matrix")
+	graphOutDir := filepath.Join(*outDir, "graph")
+	jsonldOut := filepath.Join(*outDir, "movies.jsonld.ndjson")
+	if *jsonldMode == processors.JSONLDFiles {
+		jsonldOut = filepath.Join(*outDir, "jsonld")
+	}
 
 	// Determinar fase del ETL
 	phase := "Fase 1"
@@ -87,6 +356,18 @@ func main() {
 
 	fmt.Printf("=== ETL para MongoDB - %s ===\n", phase)
 	fmt.Println()
+	switch {
+	case dataset.Source != nil:
+		reportSourceKeys(dataset.Source, *outDir, srcFlags.config(dataset.Format, *dataDir, "", "").KeysDir, *updateMappings)
+		fmt.Println()
+	case export != nil:
+		fmt.Printf("✓ Exportación NDJSON cargada: %d películas, %d usuarios, %d ratings, %d similitudes\n",
+			len(export.Movies), len(export.Users), export.Ratings, len(export.Similarities))
+		fmt.Println()
+	case dataset.Format != loaders.FormatCSV:
+		fmt.Printf("✓ Dataset %s convertido a CSV estándar (movies/ratings)\n", dataset.Format)
+		fmt.Println()
+	}
 
 	// Inicializar cliente TMDB si es necesario
 	var tmdbClient *external.TMDBClient
@@ -94,27 +375,152 @@ func main() {
 		if *tmdbAPIKey == "" {
 			fmt.Fprintln(os.Stderr, "Error: --fetch-external requiere --tmdb-api-key")
 			fmt.Fprintln(os.Stderr, "Obtén tu API key en: https://www.themoviedb.org/settings/api")
-			os.Exit(1)
+			exit(1)
 		}
 		tmdbClient = external.NewTMDBClient(*tmdbAPIKey, *tmdbRateLimit)
 		fmt.Printf("✓ Cliente TMDB inicializado (rate limit: %d req/s)\n", *tmdbRateLimit)
 		fmt.Println()
 	}
 
+	var reportSections []utils.ReportSection
+
+	// Duplicados: las entradas con claves repetidas se reemplazan por copias deduplicadas
+	if *duplicatesPolicy != "" {
+		if !validation.ValidDuplicatePolicy(*duplicatesPolicy) {
+			fmt.Fprintln(os.Stderr, "error: --duplicates debe ser keep-first, keep-last, keep-latest-timestamp o fail")
+			exit(1)
+		}
+		fmt.Println("Buscando duplicados...")
+		dedupDir, err := os.MkdirTemp("", "etl-dedup-")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error creando directorio temporal:", err)
+			exit(1)
+		}
+		atExit(func() { os.RemoveAll(dedupDir) })
+		dups, files, err := validation.CheckDuplicates(validation.InputFiles{
+			Movies:       moviesPath,
+			Ratings:      ratingsPath,
+			Links:        linksPath,
+			GenomeTags:   genomeTagsPath,
+			GenomeScores: genomeScoresPath,
+		}, *duplicatesPolicy, 5, dedupDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error resolviendo duplicados:", err)
+			exit(1)
+		}
+		printLines(dups.Lines())
+		reportSections = append(reportSections, utils.ReportSection{
+			Title: fmt.Sprintf("DUPLICADOS (política: %s)", *duplicatesPolicy),
+			Lines: dups.Lines(),
+		})
+		if err := dups.WriteJSON(filepath.Join(*outDir, "duplicates.json")); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo guardar duplicates.json: %v\n", err)
+		}
+		if total := dups.Total(); total > 0 {
+			if *duplicatesPolicy == validation.DupFail {
+				fmt.Fprintf(os.Stderr, "error: %d filas duplicadas (ver %s)\n", total, filepath.Join(*outDir, "duplicates.json"))
+				exit(1)
+			}
+			fmt.Printf("  ⚠ %d filas duplicadas descartadas (%s)\n", total, *duplicatesPolicy)
+		}
+		moviesPath, ratingsPath, linksPath = files.Movies, files.Ratings, files.Links
+		genomeTagsPath, genomeScoresPath = files.GenomeTags, files.GenomeScores
+		fmt.Println()
+	}
+
+	// Integridad referencial de las entradas (antes de procesar, para poder descartar o abortar)
+	var ratingFilter models.RatingFilter
+	if !validation.ValidPolicy(*orphansPolicy) {
+		fmt.Fprintln(os.Stderr, "error: --orphans debe ser keep, drop o fail")
+		exit(1)
+	}
+	if *checkIntegrity || *orphansPolicy != validation.PolicyKeep {
+		fmt.Println("Verificando integridad referencial...")
+		integrity := validation.CheckInputs(validation.InputFiles{
+			Movies:       moviesPath,
+			Ratings:      ratingsPath,
+			Links:        linksPath,
+			Tags:         tagsPath,
+			GenomeTags:   genomeTagsPath,
+			GenomeScores: genomeScoresPath,
+			ItemMap:      itemMapPath,
+			UserMap:      userMapPath,
+			Similarities: similaritiesPath,
+		}, 5)
+		printLines(integrity.Lines())
+		reportSections = append(reportSections, utils.ReportSection{
+			Title: fmt.Sprintf("INTEGRIDAD REFERENCIAL (política: %s)", *orphansPolicy),
+			Lines: integrity.Lines(),
+		})
+		if err := integrity.WriteJSON(filepath.Join(*outDir, "integrity.json")); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo guardar integrity.json: %v\n", err)
+		}
+		if orphans := integrity.Errors(); orphans > 0 {
+			switch *orphansPolicy {
+			case validation.PolicyFail:
+				fmt.Fprintf(os.Stderr, "error de integridad: %d referencias huérfanas (ver %s)\n", orphans, filepath.Join(*outDir, "integrity.json"))
+				exit(1)
+			case validation.PolicyDrop:
+				ratingFilter = integrity.MovieFilter()
+				fmt.Printf("  ⚠ %d referencias huérfanas: se descartan los ratings de películas inexistentes y las similitudes sin item_map\n", orphans)
+			default:
+				fmt.Printf("  ⚠ %d referencias huérfanas (se conservan; use --orphans drop|fail)\n", orphans)
+			}
+		}
+		fmt.Println()
+	}
+
+	// Validación de dominio de ratings: una instancia por pasada para no duplicar conteos
+	rules, err := ratingRules(dataset.Format, *ratingScale, *ratingInputScale, *ratingPolicy, *timestampMin, *timestampMax, *timestampPolicy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		exit(1)
+	}
+	statsValidator := validation.NewRatingValidator(rules, 5)
+	ratingsValidator := validation.NewRatingValidator(rules, 5)
+	if rules.Scale != nil {
+		fmt.Printf("✓ Escala de ratings: %s (política: %s)\n", rules.Scale, rules.RatingPolicy)
+		fmt.Println()
+	}
+
 	// Cargar datos complementarios (solo si son necesarios)
 	var links map[int]*models.Links
 	var genomeScores map[int][]models.GenomeTag
 	var userTags map[int][]string
 	var ratingStats map[int]*models.RatingStats
+	var cachedExternal map[int]*models.ExternalData
 
-	if *processMovies {
+	if *processMovies && export != nil {
+		// Reimportación: links, tags y datos de TMDB se toman de movies.ndjson
+		links, genomeScores, userTags = export.Links(), export.GenomeTags(), export.UserTags()
+		cachedExternal = export.ExternalData()
+		fmt.Printf("  ✓ Desde movies.ndjson: %d links, genome tags para %d películas, user tags para %d películas, TMDB para %d películas\n",
+			len(links), len(genomeScores), len(userTags), len(cachedExternal))
+
+		if export.Ratings > 0 {
+			fmt.Println("Recalculando estadísticas de ratings...")
+			var err error
+			ratingStats, err = loaders.LoadRatingStats(ratingsPath, validation.ChainFilters(ratingFilter, statsValidator))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Advertencia: no se pudo recalcular estadísticas: %v\n", err)
+				ratingStats = export.RatingStats()
+			}
+		} else {
+			ratingStats = export.RatingStats()
+		}
+		fmt.Printf("  ✓ Estadísticas para %d películas\n", len(ratingStats))
+	} else if *processMovies {
 		fmt.Println("Cargando links...")
 		var err error
-		links, err = loaders.LoadLinks(linksPath)
+		var overwritten int
+		links, overwritten, err = loaders.LoadLinks(linksPath)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar links.csv: %v\n", err)
 			links = make(map[int]*models.Links)
 		}
+		if overwritten > 0 {
+			fmt.Fprintf(os.Stderr, "Advertencia: %d movieId repetidos en links.csv (se conserva el último; use --duplicates)\n", overwritten)
+		}
 		fmt.Printf("  ✓ %d links cargados\n", len(links))
 
 		fmt.Println("Cargando genome tags...")
@@ -134,15 +540,40 @@ func main() {
 		fmt.Printf("  ✓ Genome scores cargados para %d películas (relevancia >= %.2f)\n", len(genomeScores), *minRelevance)
 
 		fmt.Println("Cargando user tags...")
-		userTags, err = loaders.LoadUserTags(tagsPath)
+		tagRules := loaders.DefaultTagRules()
+		tagRules.FuzzyDistance = *tagFuzzyDistance
+		if *tagSynonymsFile != "" {
+			if err := tagRules.LoadTagSynonyms(inputs.Resolve(*dataDir, *tagSynonymsFile)); err != nil {
+				fmt.Fprintln(os.Stderr, "error cargando sinónimos de tags:", err)
+				exit(1)
+			}
+		}
+		if *tagBlocklistFile != "" {
+			if err := tagRules.LoadTagBlocklist(inputs.Resolve(*dataDir, *tagBlocklistFile)); err != nil {
+				fmt.Fprintln(os.Stderr, "error cargando lista de bloqueo de tags:", err)
+				exit(1)
+			}
+		}
+		var tagReport *loaders.TagReport
+		userTags, tagReport, err = loaders.LoadUserTags(tagsPath, tagRules)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar tags.csv: %v\n", err)
 			userTags = make(map[int][]string)
 		}
 		fmt.Printf("  ✓ User tags cargados para %d películas\n", len(userTags))
+		if tagReport != nil {
+			printLines(tagReport.Lines())
+			reportSections = append(reportSections, utils.ReportSection{
+				Title: "USER TAGS",
+				Lines: tagReport.Lines(),
+			})
+			if err := tagReport.WriteJSON(filepath.Join(*outDir, "tag_merges.json")); err != nil {
+				fmt.Fprintf(os.Stderr, "Advertencia: no se pudo guardar tag_merges.json: %v\n", err)
+			}
+		}
 
 		fmt.Println("Calculando estadísticas de ratings...")
-		ratingStats, err = loaders.LoadRatingStats(ratingsPath)
+		ratingStats, err = loaders.LoadRatingStats(ratingsPath, validation.ChainFilters(ratingFilter, statsValidator))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar ratings.csv: %v\n", err)
 			ratingStats = make(map[int]*models.RatingStats)
@@ -153,27 +584,116 @@ func main() {
 	// Cargar mapeos (siempre necesarios si hay algún procesador activo)
 	var itemMapper *mappers.IDMapper
 	var userMapper *mappers.IDMapper
+	var loadedMaps []validation.Mapping
+	if !mappers.ValidIndexBase(*indexBase) {
+		fmt.Fprintln(os.Stderr, "error: --index-base debe ser 0 o 1")
+		exit(1)
+	}
+
+	if *userBuckets && !*processRatings {
+		fmt.Fprintln(os.Stderr, "Advertencia: --user-buckets requiere --process-ratings, se omite user_ratings.ndjson")
+		*userBuckets = false
+	}
+	if *exportMatrix && !*processRatings {
+		fmt.Fprintln(os.Stderr, "Advertencia: --export-matrix requiere --process-ratings, se omite la matriz")
+		*exportMatrix = false
+	}
 
-	if *processMovies || *processSimilarities {
+	if *processMovies || *processSimilarities || *userBuckets || *exportMatrix {
 		fmt.Println("Cargando mapeo de items...")
 		itemMap, err := loaders.LoadItemMap(itemMapPath)
+		if export != nil {
+			// Los iIdx de la exportación son los que usan sus documentos
+			itemMap, err = export.ItemMap(), nil
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar item_map.csv: %v\n", err)
 			itemMap = make(map[int]int)
 		}
 		fmt.Printf("  ✓ Mapeo de items cargado para %d películas\n", len(itemMap))
-		itemMapper = mappers.NewIDMapper(itemMap)
+		itemMapper = mappers.NewIDMapper(itemMap, *indexBase)
+		if export == nil {
+			mapping := validation.Mapping{Name: "item_map", IDColumn: "movieId", IdxColumn: "iIdx", Map: itemMap}
+			if movies, err := validation.DatasetIDs(moviesPath, "movies", "movieId"); err == nil {
+				mapping.Dataset = movies
+			}
+			loadedMaps = append(loadedMaps, mapping)
+		}
 	}
 
-	if *processUsers {
+	if *processUsers || *userBuckets || *exportMatrix {
 		fmt.Println("Cargando mapeo de usuarios...")
 		userMap, err := loaders.LoadUserMap(userMapPath)
+		if export != nil {
+			userMap, err = export.UserMap(), nil
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar user_map.csv: %v\n", err)
 			userMap = make(map[int]int)
 		}
 		fmt.Printf("  ✓ Mapeo de usuarios cargado para %d usuarios\n", len(userMap))
-		userMapper = mappers.NewIDMapper(userMap)
+		userMapper = mappers.NewIDMapper(userMap, *indexBase)
+		if export == nil {
+			// Los usuarios del dataset requieren recorrer ratings.csv: solo en "mappings check"
+			loadedMaps = append(loadedMaps, validation.Mapping{Name: "user_map", IDColumn: "userId", IdxColumn: "uIdx", Map: userMap})
+		}
+	}
+
+	// Los índices de los mapeos deben formar un rango compacto desde --index-base, igual que los
+	// de las similitudes (ver "mappings check|repair")
+	if len(loadedMaps) > 0 {
+		similaritiesCheck := ""
+		if *processSimilarities || (*processMovies && *embedSimilar > 0) {
+			similaritiesCheck = similaritiesPath
+		}
+		mappingReport := validation.CheckMappings(loadedMaps, *indexBase, similaritiesCheck, 5)
+		if problems := mappingReport.Errors(); problems > 0 {
+			fmt.Fprintf(os.Stderr, "Advertencia: %d problemas en los mapeos de índices (use \"mappings repair\" para compactarlos)\n", problems)
+			printLines(mappingReport.Lines())
+			reportSections = append(reportSections, utils.ReportSection{
+				Title: "MAPEOS DE ÍNDICES",
+				Lines: mappingReport.Lines(),
+			})
+		}
+	}
+
+	// Cargar similitudes (para similarities.ndjson y/o vecinos embebidos en movies)
+	var similarities map[int][]models.Neighbor
+	if export != nil && (*processSimilarities || (*processMovies && *embedSimilar > 0)) {
+		similarities = export.SimilarityMap()
+		fmt.Printf("  ✓ Similitudes reconstruidas desde similarities.ndjson para %d películas\n", len(similarities))
+	} else if *processSimilarities || (*processMovies && *embedSimilar > 0) {
+		// ProcessMovies asigna los iIdx faltantes, pero las similitudes se cargan antes (para
+		// embeberlas): se asignan aquí en el mismo orden para no descartar sus filas
+		if *processMovies {
+			if added, err := loaders.AssignItemIndices(moviesPath, itemMapper); err != nil {
+				fmt.Fprintf(os.Stderr, "Advertencia: no se pudieron asignar iIdx desde movies.csv: %v\n", err)
+			} else if added > 0 {
+				fmt.Printf("  ✓ %d iIdx nuevos asignados desde movies.csv\n", added)
+			}
+		}
+		fmt.Println("Cargando similitudes desde", similaritiesPath, "...")
+		var serr error
+		similarities, serr = loaders.LoadSimilarities(similaritiesPath, itemMapper, *orphansPolicy == validation.PolicyDrop)
+		if serr != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar similitudes: %v\n", serr)
+			similarities = make(map[int][]models.Neighbor)
+		}
+		fmt.Printf("  ✓ Similitudes cargadas para %d películas\n", len(similarities))
+	}
+
+	// Taxonomía de géneros
+	if !taxonomy.ValidFormat(*genresFormat) {
+		fmt.Fprintln(os.Stderr, "error: --genres-format debe ser raw, canonical u objects")
+		exit(1)
+	}
+	genreTaxonomy := taxonomy.Default()
+	if *genreTaxonomyFile != "" {
+		genreTaxonomy, err = taxonomy.Load(inputs.Resolve(*dataDir, *genreTaxonomyFile))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error cargando taxonomía de géneros:", err)
+			exit(1)
+		}
 	}
 
 	// Cargar géneros únicos si se van a procesar usuarios
@@ -182,15 +702,34 @@ func main() {
 		fmt.Println("Extrayendo géneros únicos de movies.csv...")
 		var err error
 		allGenres, err = loaders.ExtractUniqueGenres(moviesPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Advertencia: no se pudieron extraer géneros: %v\n", err)
-			allGenres = []string{"Action", "Adventure", "Comedy", "Drama", "Thriller"} // Fallback
+		if err != nil || len(allGenres) == 0 {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudieron extraer géneros (se usan los de la taxonomía): %v\n", err)
+			allGenres = genreTaxonomy.Names(*genresFormat)
+		} else if *genresFormat != taxonomy.FormatRaw {
+			allGenres = genreTaxonomy.IDs(allGenres)
 		}
 		fmt.Printf("  ✓ %d géneros únicos extraídos\n", len(allGenres))
 	}
 
 	// Procesar archivos según flags
 	var mcount, rcount, ucount, scount int
+	var extras []utils.ExtraOutput
+
+	// Exportador a grafo (recibe movies, ratings y similarities a medida que se generan)
+	var graphExporter *processors.GraphExporter
+	if *exportGraph {
+		var gerr error
+		graphExporter, gerr = processors.NewGraphExporter(graphOutDir)
+		if gerr != nil {
+			fmt.Fprintln(os.Stderr, "error preparando exportación a grafo:", gerr)
+			exit(1)
+		}
+	}
+
+	var profiler *processors.Profiler
+	if *profileDataset {
+		profiler = processors.NewProfiler()
+	}
 
 	if *processMovies {
 		fmt.Println()
@@ -200,13 +739,59 @@ func main() {
 		} else {
 			fmt.Println("Procesando movies:", moviesPath)
 		}
+		var movieSinks []processors.MovieSink
+		if graphExporter != nil {
+			movieSinks = append(movieSinks, graphExporter)
+		}
+		if profiler != nil {
+			movieSinks = append(movieSinks, profiler)
+		}
+		var jsonldWriter *processors.JSONLDWriter
+		if *jsonldMode != "" {
+			// aggregateRating publica la escala resuelta (ml-100k/ml-1m: 1-5); sin escala, la de MovieLens
+			scale := validation.ScaleMovieLens
+			if rules.Scale != nil {
+				scale = *rules.Scale
+			}
+			var jerr error
+			jsonldWriter, jerr = processors.NewJSONLDWriter(*jsonldMode, jsonldOut, scale.Max, scale.Min)
+			if jerr != nil {
+				fmt.Fprintln(os.Stderr, "error preparando salida JSON-LD:", jerr)
+				exit(1)
+			}
+			movieSinks = append(movieSinks, jsonldWriter)
+		}
 		var merr error
-		mcount, merr = processors.ProcessMovies(moviesPath, moviesOut, links, genomeScores, userTags, ratingStats, itemMapper, *topGenomeTags, tmdbClient, *fetchExternal, yearRe)
+		mcount, merr = processors.ProcessMovies(moviesPath, moviesOut, links, genomeScores, userTags, ratingStats, itemMapper, *topGenomeTags, tmdbClient, *fetchExternal, cachedExternal, yearRe, genreTaxonomy, *genresFormat, similarities, *embedSimilar, movieSinks...)
 		if merr != nil {
 			fmt.Fprintln(os.Stderr, "error procesando movies:", merr)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Printf("  ✓ Escritas %d películas en %s\n", mcount, moviesOut)
+		if profiler != nil {
+			profiler.FinishMovies()
+		}
+		printLines(genreTaxonomy.Lines(*genresFormat))
+		reportSections = append(reportSections, utils.ReportSection{
+			Title: "GÉNEROS",
+			Lines: genreTaxonomy.Lines(*genresFormat),
+		})
+		if *embedSimilar > 0 {
+			fmt.Printf("  ✓ Top %d películas similares embebidas en cada documento\n", *embedSimilar)
+		}
+		if jsonldWriter != nil {
+			if err := jsonldWriter.Close(); err != nil {
+				fmt.Fprintln(os.Stderr, "error escribiendo JSON-LD:", err)
+				exit(1)
+			}
+			fmt.Printf("  ✓ %d películas en schema.org JSON-LD en %s\n", jsonldWriter.Count(), jsonldOut)
+			extras = append(extras, utils.ExtraOutput{
+				Label:       "JSON-LD",
+				File:        filepath.Base(jsonldOut),
+				Description: "schema.org Movie (datos estructurados para SEO)",
+				Count:       jsonldWriter.Count(),
+			})
+		}
 	} else {
 		fmt.Println()
 		fmt.Println("⏭ Procesamiento de movies omitido (--process-movies=false)")
@@ -215,13 +800,75 @@ func main() {
 	if *processRatings {
 		fmt.Println()
 		fmt.Println("Procesando ratings:", ratingsPath)
+		// Los buckets y la matriz solo usan índices existentes: los uIdx que ProcessUsers asignará
+		// después se asignan aquí en el mismo orden (userId ascendente, antes de filtrar ratings)
+		if (*userBuckets || *exportMatrix) && *processUsers {
+			if added, err := loaders.AssignUserIndices(ratingsPath, userMapper); err != nil {
+				fmt.Fprintf(os.Stderr, "Advertencia: no se pudieron asignar uIdx desde ratings.csv: %v\n", err)
+			} else if added > 0 {
+				fmt.Printf("  ✓ %d uIdx nuevos asignados desde ratings.csv\n", added)
+			}
+		}
+		var sinks []processors.RatingSink
+		var bucketWriter *processors.UserBucketWriter
+		if *userBuckets {
+			var berr error
+			bucketWriter, berr = processors.NewUserBucketWriter(userRatingsOut, *bucketSize, userMapper, itemMapper)
+			if berr != nil {
+				fmt.Fprintln(os.Stderr, "error creando user_ratings.ndjson:", berr)
+				exit(1)
+			}
+			sinks = append(sinks, bucketWriter)
+		}
+		if graphExporter != nil {
+			sinks = append(sinks, graphExporter.Ratings())
+		}
+		if profiler != nil {
+			sinks = append(sinks, profiler)
+		}
+		var matrixExporter *processors.MatrixExporter
+		if *exportMatrix {
+			var xerr error
+			matrixExporter, xerr = processors.NewMatrixExporter(matrixOutDir, userMapper, itemMapper)
+			if xerr != nil {
+				fmt.Fprintln(os.Stderr, "error preparando exportación de matriz:", xerr)
+				exit(1)
+			}
+			sinks = append(sinks, matrixExporter)
+		}
 		var rerr error
-		rcount, rerr = processors.ProcessRatings(ratingsPath, ratingsOut)
+		rcount, rerr = processors.ProcessRatings(ratingsPath, ratingsOut, validation.ChainFilters(ratingFilter, ratingsValidator), sinks...)
 		if rerr != nil {
 			fmt.Fprintln(os.Stderr, "error procesando ratings:", rerr)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Printf("  ✓ Escritas %d entradas en %s\n", rcount, ratingsOut)
+		if bucketWriter != nil {
+			fmt.Printf("  ✓ Escritos %d buckets de usuario (máx. %d ratings) en %s\n", bucketWriter.Count(), *bucketSize, userRatingsOut)
+			extras = append(extras, utils.ExtraOutput{
+				Label:       "User buckets",
+				File:        "user_ratings.ndjson",
+				Description: fmt.Sprintf("Historial de ratings por usuario (buckets de %d)", *bucketSize),
+				Count:       bucketWriter.Count(),
+			})
+		}
+		if matrixExporter != nil {
+			meta := matrixExporter.Meta()
+			fmt.Printf("  ✓ Matriz %dx%d con %d valores exportada en %s (MatrixMarket + CSR)\n", meta.Rows, meta.Cols, meta.NNZ, matrixOutDir)
+			if meta.Unmapped > 0 {
+				fmt.Printf("  ⚠ %d ratings fuera de la matriz: userId/movieId sin uIdx/iIdx en los mapeos\n", meta.Unmapped)
+			}
+			if meta.Duplicates > 0 {
+				fmt.Printf("  ⚠ %d pares (uIdx, iIdx) repetidos en la matriz: se conserva el último rating\n", meta.Duplicates)
+			}
+			extras = append(extras, utils.ExtraOutput{
+				Label:       "Matriz (nnz)",
+				File:        "matrix/",
+				Description: fmt.Sprintf("Matriz de ratings %dx%d: ratings.mtx, CSR binario e índices", meta.Rows, meta.Cols),
+				Count:       meta.NNZ,
+				Unit:        "valores no nulos",
+			})
+		}
 	} else {
 		fmt.Println()
 		fmt.Println("⏭ Procesamiento de ratings omitido (--process-ratings=false)")
@@ -231,10 +878,23 @@ func main() {
 		fmt.Println()
 		fmt.Println("Generando users con passwords hasheados...")
 		var uerr error
-		ucount, uerr = processors.ProcessUsers(ratingsPath, usersOut, passwordLogOut, userMapper, *hashPasswords, allGenres)
+		var existingUsers map[int]*models.UserDoc
+		if export != nil {
+			existingUsers = export.UserDocs()
+		}
+		var demographics map[int]models.Demographics
+		if dataset.Users != "" {
+			var derr error
+			if demographics, derr = loaders.LoadDemographics(dataset.Users); derr != nil {
+				fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar la demografía de usuarios: %v\n", derr)
+			} else {
+				fmt.Printf("  ✓ Demografía cargada para %d usuarios\n", len(demographics))
+			}
+		}
+		ucount, uerr = processors.ProcessUsers(ratingsPath, usersOut, passwordLogOut, userMapper, *hashPasswords, allGenres, existingUsers, demographics)
 		if uerr != nil {
 			fmt.Fprintln(os.Stderr, "error generando users:", uerr)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Printf("  ✓ Generados %d usuarios en %s\n", ucount, usersOut)
 		if *hashPasswords {
@@ -250,20 +910,16 @@ func main() {
 
 	if *processSimilarities {
 		fmt.Println()
-		fmt.Println("Cargando similitudes desde", similaritiesPath, "...")
-		similarities, serr := loaders.LoadSimilarities(similaritiesPath, itemMapper)
-		if serr != nil {
-			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo cargar similitudes: %v\n", serr)
-			similarities = make(map[int][]models.Neighbor)
-		}
-		fmt.Printf("  ✓ Similitudes cargadas para %d películas\n", len(similarities))
-
 		fmt.Println("Generando similarities...")
+		var simSinks []processors.SimilaritySink
+		if graphExporter != nil {
+			simSinks = append(simSinks, graphExporter)
+		}
 		var serr2 error
-		scount, serr2 = processors.ProcessSimilarities(similaritiesOut, similarities, itemMapper)
+		scount, serr2 = processors.ProcessSimilarities(similaritiesOut, similarities, itemMapper, simSinks...)
 		if serr2 != nil {
 			fmt.Fprintln(os.Stderr, "error generando similarities:", serr2)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Printf("  ✓ Generadas %d entradas de similitud en %s\n", scount, similaritiesOut)
 	} else {
@@ -271,6 +927,26 @@ func main() {
 		fmt.Println("⏭ Procesamiento de similarities omitido (--process-similarities=false)")
 	}
 
+	if graphExporter != nil {
+		fmt.Println()
+		fmt.Println("Cerrando exportación a grafo...")
+		if err := graphExporter.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "error escribiendo CSVs de grafo:", err)
+			exit(1)
+		}
+		counts := graphExporter.Counts()
+		nodes := counts["movies.csv"] + counts["users.csv"] + counts["genres.csv"] + counts["tags.csv"]
+		rels := counts["rated.csv"] + counts["similar_to.csv"] + counts["in_genre.csv"] + counts["tagged.csv"]
+		fmt.Printf("  ✓ %d nodos y %d relaciones escritos en %s (ver neo4j-import.sh)\n", nodes, rels, graphOutDir)
+		extras = append(extras, utils.ExtraOutput{
+			Label:       "Grafo",
+			File:        "graph/",
+			Description: fmt.Sprintf("CSVs para neo4j-admin import (%d nodos, %d relaciones)", nodes, rels),
+			Count:       nodes + rels,
+			Unit:        "nodos + relaciones",
+		})
+	}
+
 	// Persistir mapeos si fueron modificados y el flag está activo
 	if *updateMappings {
 		if itemMapper != nil && itemMapper.HasChanged() {
@@ -292,10 +968,68 @@ func main() {
 		}
 	}
 
+	// Generar scripts de importación (setup.js, import.sh, import.ps1)
+	var collections []string
+	if *processMovies {
+		collections = append(collections, "movies")
+	}
+	if *processRatings {
+		collections = append(collections, "ratings")
+	}
+	if *processUsers {
+		collections = append(collections, "users")
+	}
+	if *processSimilarities {
+		collections = append(collections, "similarities")
+	}
+	if *userBuckets {
+		collections = append(collections, "user_ratings")
+	}
+	fmt.Println()
+	fmt.Println("Generando scripts de importación...")
+	if scripts, err := utils.GenerateImportScripts(*outDir, *dbName, collections); err != nil {
+		fmt.Fprintf(os.Stderr, "Advertencia: no se pudieron generar scripts de importación: %v\n", err)
+	} else {
+		for _, script := range scripts {
+			fmt.Printf("  ✓ %s\n", script)
+		}
+	}
+
 	// Generar reporte final
+	if profiler != nil {
+		profileOut := filepath.Join(*outDir, "profile.json")
+		fmt.Println("Perfil del dataset:")
+		printLines(profiler.Lines())
+		if err := profiler.WriteJSON(profileOut); err != nil {
+			fmt.Fprintf(os.Stderr, "Advertencia: no se pudo guardar profile.json: %v\n", err)
+		} else {
+			fmt.Printf("  ✓ Perfil guardado en %s\n", profileOut)
+		}
+		fmt.Println()
+		reportSections = append(reportSections, utils.ReportSection{
+			Title: "PERFIL DEL DATASET",
+			Lines: profiler.Lines(),
+		})
+	}
+
+	// Resumen de la validación de dominio (de la pasada de ratings, o de la de estadísticas)
+	validator := ratingsValidator
+	if validator.Checked == 0 {
+		validator = statsValidator
+	}
+	if validator.Checked > 0 {
+		fmt.Println("Validación de ratings:")
+		printLines(validator.Lines())
+		fmt.Println()
+		reportSections = append(reportSections, utils.ReportSection{
+			Title: "VALIDACIÓN DE RATINGS",
+			Lines: validator.Lines(),
+		})
+	}
+
 	elapsedTime := time.Since(startTime)
 	reportPath := filepath.Join(*outDir, "report.txt")
-	if err := utils.GenerateReport(reportPath, mcount, rcount, ucount, scount, *hashPasswords, *fetchExternal, *processMovies, *processRatings, *processUsers, *processSimilarities, elapsedTime); err != nil {
+	if err := utils.GenerateReport(reportPath, *dbName, mcount, rcount, ucount, scount, *hashPasswords, *fetchExternal, *processMovies, *processRatings, *processUsers, *processSimilarities, extras, append(reportSections, inputsReportSection()), elapsedTime); err != nil {
 		fmt.Fprintf(os.Stderr, "Advertencia: no se pudo generar reporte: %v\n", err)
 	} else {
 		fmt.Printf("\n  ✓ Reporte generado en %s\n", reportPath)
@@ -305,3 +1039,56 @@ func main() {
 	fmt.Println("=== ETL completado exitosamente ===")
 	fmt.Printf("Tiempo total de ejecución: %s\n", utils.FormatDuration(elapsedTime))
 }
+
+// ratingRules construye las reglas de dominio de ratings; la escala "auto" depende del formato
+// (ml-100k/ml-1m usan 1-5 enteros, MovieLens CSV y NDJSON 0.5-5.0, las fuentes genéricas no se validan)
+func ratingRules(format, scaleSpec, inputScaleSpec, ratingPolicy, tsMin, tsMax, tsPolicy string) (validation.RatingRules, error) {
+	rules := validation.RatingRules{RatingPolicy: ratingPolicy, TimestampPolicy: tsPolicy}
+	switch ratingPolicy {
+	case validation.RatingKeep, validation.RatingDrop, validation.RatingRescale:
+	default:
+		return rules, fmt.Errorf("--rating-policy debe ser keep, drop o rescale")
+	}
+	if tsPolicy != validation.RatingKeep && tsPolicy != validation.RatingDrop {
+		return rules, fmt.Errorf("--timestamp-policy debe ser keep o drop")
+	}
+
+	switch scaleSpec {
+	case "none":
+	case "auto", "":
+		switch format {
+		case loaders.FormatML100K, loaders.FormatML1M:
+			scale := validation.ScaleFiveStar
+			rules.Scale = &scale
+		case loaders.FormatCSV, loaders.FormatNDJSON:
+			scale := validation.ScaleMovieLens
+			rules.Scale = &scale
+		}
+	default:
+		scale, err := validation.ParseRatingScale(scaleSpec)
+		if err != nil {
+			return rules, fmt.Errorf("--rating-scale: %w", err)
+		}
+		rules.Scale = &scale
+	}
+
+	if inputScaleSpec != "" {
+		if rules.Scale == nil {
+			return rules, fmt.Errorf("--rating-input-scale requiere una --rating-scale de destino")
+		}
+		scale, err := validation.ParseRatingScale(inputScaleSpec)
+		if err != nil {
+			return rules, fmt.Errorf("--rating-input-scale: %w", err)
+		}
+		rules.InputScale = &scale
+	}
+
+	var err error
+	if rules.MinTimestamp, err = validation.ParseTimestamp(tsMin); err != nil {
+		return rules, fmt.Errorf("--timestamp-min: %w", err)
+	}
+	if rules.MaxTimestamp, err = validation.ParseTimestamp(tsMax); err != nil {
+		return rules, fmt.Errorf("--timestamp-max: %w", err)
+	}
+	return rules, nil
+}